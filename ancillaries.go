@@ -0,0 +1,152 @@
+package wingo
+
+import (
+	"sort"
+	"strings"
+)
+
+type AncillaryKind string
+
+const (
+	AncillaryCheckedBag AncillaryKind = "checked_bag"
+	AncillaryCarryOn    AncillaryKind = "carry_on"
+	AncillarySeat       AncillaryKind = "seat"
+	AncillarySports     AncillaryKind = "sports_equipment"
+	AncillaryPet        AncillaryKind = "pet"
+	AncillaryOther      AncillaryKind = "other"
+)
+
+// IsBaggage reports whether k is a checked bag or a carry-on.
+func (k AncillaryKind) IsBaggage() bool {
+	return k == AncillaryCheckedBag || k == AncillaryCarryOn
+}
+
+// ancillaryKeywords are matched, in order, against the code and description
+// of a service to find out its kind.
+var ancillaryKeywords = []struct {
+	kind     AncillaryKind
+	keywords []string
+}{
+	{AncillaryCarryOn, []string{"CARRY", "MANO", "CABINA", "CABIN"}},
+	{AncillaryCheckedBag, []string{"BAG", "BODEGA", "MALETA", "EQUIPAJE", "LUGGAGE"}},
+	{AncillarySeat, []string{"SEAT", "SILLA", "ASIENTO"}},
+	{AncillarySports, []string{"SPORT", "DEPORT", "BICI", "GOLF", "SURF"}},
+	{AncillaryPet, []string{"PET", "MASCOTA", "AVIH", "PETC"}},
+}
+
+func ancillaryKind(service Service) AncillaryKind {
+	text := strings.ToUpper(service.CodeType + " " + service.Description)
+	for _, k := range ancillaryKeywords {
+		for _, keyword := range k.keywords {
+			if strings.Contains(text, keyword) {
+				return k.kind
+			}
+		}
+	}
+	return AncillaryOther
+}
+
+// Ancillary is an optional service sold with a flight.
+type Ancillary struct {
+	Kind        AncillaryKind `json:"kind"`
+	Code        string        `json:"code"`
+	Description string        `json:"description"`
+	// Price per passenger, including taxes.
+	Price     float64 `json:"price"`
+	Available int64   `json:"available"`
+}
+
+// Ancillaries is a catalog of ancillaries sorted by code.
+type Ancillaries []Ancillary
+
+// NewAncillaries builds the catalog of the services quoted for a flight,
+// leaving out the admin fares and the bundles.
+func NewAncillaries(services []Service) Ancillaries {
+	ancillaries := Ancillaries{}
+	for _, service := range services {
+		if service.CodeType == AdminFareCode || isBundleService(service) {
+			continue
+		}
+
+		ancillaries = append(ancillaries, Ancillary{
+			Kind:        ancillaryKind(service),
+			Code:        service.CodeType,
+			Description: service.Description,
+			Price:       sumarServicio(service),
+			Available:   service.AvalaibleQuantity,
+		})
+	}
+
+	sort.SliceStable(ancillaries, func(i, j int) bool {
+		return ancillaries[i].Code < ancillaries[j].Code
+	})
+	return ancillaries
+}
+
+func isBundleService(service Service) bool {
+	for _, bundle := range Bundles {
+		if bundle != BundleBasic && matchesBundle(service, bundle) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Ancillaries) Find(code string) (Ancillary, bool) {
+	for _, ancillary := range a {
+		if ancillary.Code == code {
+			return ancillary, true
+		}
+	}
+	return Ancillary{}, false
+}
+
+// Filter returns the ancillaries of the given kinds.
+func (a Ancillaries) Filter(fn func(AncillaryKind) bool) Ancillaries {
+	filtered := Ancillaries{}
+	for _, ancillary := range a {
+		if fn(ancillary.Kind) {
+			filtered = append(filtered, ancillary)
+		}
+	}
+	return filtered
+}
+
+// AncillaryChange is the difference of an ancillary between two catalogs. An
+// added ancillary has no Old value and a removed one has no New value.
+type AncillaryChange struct {
+	Old *Ancillary `json:"old,omitempty"`
+	New *Ancillary `json:"new,omitempty"`
+}
+
+func (c AncillaryChange) Ancillary() Ancillary {
+	if c.New != nil {
+		return *c.New
+	}
+	return *c.Old
+}
+
+// DiffAncillaries returns the ancillaries added, removed or whose price
+// changed from old to new.
+func DiffAncillaries(old, new Ancillaries) []AncillaryChange {
+	changes := []AncillaryChange{}
+
+	for i := range new {
+		current := new[i]
+		previous, found := old.Find(current.Code)
+		if !found {
+			changes = append(changes, AncillaryChange{New: &current})
+		} else if previous.Price != current.Price {
+			changes = append(changes, AncillaryChange{Old: &previous, New: &current})
+		}
+	}
+
+	for i := range old {
+		previous := old[i]
+		if _, found := new.Find(previous.Code); !found {
+			changes = append(changes, AncillaryChange{Old: &previous})
+		}
+	}
+
+	return changes
+}
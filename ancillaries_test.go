@@ -0,0 +1,50 @@
+package wingo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAncillaries(t *testing.T) {
+	ancillaries := NewAncillaries([]Service{
+		{CodeType: AdminFareCode, Amount: 10},
+		{CodeType: "XBAG", Description: "Equipaje de bodega 23kg", Amount: 100, Taxes: []Tax{{TaxAmount: 19}}, AvalaibleQuantity: 3},
+		{CodeType: "CLAS", Description: "Tarifa Classic", Amount: 40},
+		{CodeType: "CBAG", Description: "Equipaje de mano 10kg", Amount: 50},
+		{CodeType: "STST", Description: "Selección de silla", Amount: 20},
+		{CodeType: "INS", Description: "Seguro de viaje", Amount: 30},
+	})
+
+	require.Len(t, ancillaries, 4)
+	assert.Equal(t, Ancillary{Kind: AncillaryCarryOn, Code: "CBAG", Description: "Equipaje de mano 10kg", Price: 50}, ancillaries[0])
+	assert.Equal(t, AncillaryOther, ancillaries[1].Kind)
+	assert.Equal(t, AncillarySeat, ancillaries[2].Kind)
+	assert.Equal(t, Ancillary{Kind: AncillaryCheckedBag, Code: "XBAG", Description: "Equipaje de bodega 23kg", Price: 119, Available: 3}, ancillaries[3])
+	assert.Len(t, ancillaries.Filter(AncillaryKind.IsBaggage), 2)
+}
+
+func TestDiffAncillaries(t *testing.T) {
+	old := Ancillaries{
+		{Kind: AncillaryCarryOn, Code: "CBAG", Price: 50},
+		{Kind: AncillarySeat, Code: "STST", Price: 20},
+		{Kind: AncillaryCheckedBag, Code: "XBAG", Price: 100},
+	}
+	new := Ancillaries{
+		{Kind: AncillaryCarryOn, Code: "CBAG", Price: 50},
+		{Kind: AncillaryPet, Code: "PETC", Price: 200},
+		{Kind: AncillaryCheckedBag, Code: "XBAG", Price: 120},
+	}
+
+	changes := DiffAncillaries(old, new)
+	require.Len(t, changes, 3)
+	assert.Nil(t, changes[0].Old)
+	assert.Equal(t, "PETC", changes[0].New.Code)
+	assert.Equal(t, 100.0, changes[1].Old.Price)
+	assert.Equal(t, 120.0, changes[1].New.Price)
+	assert.Nil(t, changes[2].New)
+	assert.Equal(t, "STST", changes[2].Ancillary().Code)
+
+	assert.Empty(t, DiffAncillaries(old, old))
+}
@@ -0,0 +1,134 @@
+package wingo
+
+import "strings"
+
+// Fare families sold by Wingo. BundleBasic only includes the fare, the others
+// are priced from the services returned by RetrieveServiceQuotes.
+const (
+	BundleBasic   = OriginalPlanName
+	BundleClassic = "CLASSIC"
+	BundlePlus    = "PLUS"
+)
+
+var Bundles = []string{BundleBasic, BundleClassic, BundlePlus}
+
+// bundleAliases are other names Wingo uses for the same fare family.
+var bundleAliases = map[string][]string{
+	BundlePlus: {"CLUB"},
+}
+
+// NormalizeBundle returns the upper case name of bundle, or BundleBasic when
+// it is empty.
+func NormalizeBundle(bundle string) string {
+	bundle = strings.ToUpper(strings.TrimSpace(bundle))
+	if bundle == "" {
+		return BundleBasic
+	}
+	for name, aliases := range bundleAliases {
+		for _, alias := range aliases {
+			if bundle == alias {
+				return name
+			}
+		}
+	}
+	return bundle
+}
+
+func IsBundle(bundle string) bool {
+	bundle = NormalizeBundle(bundle)
+	for _, b := range Bundles {
+		if b == bundle {
+			return true
+		}
+	}
+	return false
+}
+
+func sumarServicio(service Service) float64 {
+	var taxes float64
+	for _, tax := range service.Taxes {
+		taxes += tax.TaxAmount
+	}
+	return service.Amount + taxes
+}
+
+// bundleServiceCodes are the codes of the services that price each bundle.
+var bundleServiceCodes = map[string][]string{
+	BundleClassic: {"CLAS", BundleClassic},
+	BundlePlus:    {"CLUB", BundlePlus},
+}
+
+// matchesBundle reports whether service prices bundle. Only the codes are
+// compared: the descriptions are localized free text.
+func matchesBundle(service Service, bundle string) bool {
+	for _, code := range bundleServiceCodes[bundle] {
+		if strings.EqualFold(strings.TrimSpace(service.CodeType), code) {
+			return true
+		}
+	}
+	return false
+}
+
+// getBundlePriceByTitle returns the price per passenger of bundle, looking
+// for the service with its code.
+func getBundlePriceByTitle(bundle string, services []Service) (float64, bool) {
+	for _, service := range services {
+		if service.CodeType == AdminFareCode {
+			continue
+		}
+		if matchesBundle(service, bundle) {
+			return sumarServicio(service), true
+		}
+	}
+	return 0, false
+}
+
+// GetPassengerBundleFare returns the price of flight in the given bundle for
+// the single passenger of passenger (e.g. Passengers{Children: 1}), without
+// the admin fares, and whether the fare and the bundle are available.
+func GetPassengerBundleFare(bundle string, flight Vuelo, services []Service, passenger Passengers) (float64, bool) {
+	price, available := SumarPrecioPasajeros(flight, passenger)
+	if !available {
+		return 0, false
+	}
+
+	// infants do not take a seat nor carry their own luggage
+	bundle = NormalizeBundle(bundle)
+	if bundle != BundleBasic && passenger.Infants == 0 {
+		bundlePrice, found := getBundlePriceByTitle(bundle, services)
+		if !found {
+			return 0, false
+		}
+		price += bundlePrice
+	}
+
+	return price, true
+}
+
+func GetBundlePrice(bundle string, flight Vuelo, services []Service) (float64, bool) {
+	return GetPassengersBundlePrice(bundle, flight, services, SingleAdult)
+}
+
+// GetPassengersBundlePrice returns the price of flight in the given bundle
+// for every passenger, including the admin fares, and whether the fares and
+// the bundle are available.
+func GetPassengersBundlePrice(bundle string, flight Vuelo, services []Service, passengers Passengers) (float64, bool) {
+	price, available := SumarPrecioPasajeros(flight, passengers)
+	if !available {
+		return 0, false
+	}
+
+	bundle = NormalizeBundle(bundle)
+	if bundle != BundleBasic {
+		bundlePrice, found := getBundlePriceByTitle(bundle, services)
+		if !found {
+			return 0, false
+		}
+
+		// infants do not take a seat nor carry their own luggage
+		passengers = passengers.OrDefault()
+		price += bundlePrice * float64(passengers.Adults+passengers.Children)
+	}
+
+	return price + GetAdminFares(ServiceQuote{Services: services}), true
+}
@@ -0,0 +1,91 @@
+package wingo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPassengersBundlePrice(t *testing.T) {
+	flight := Vuelo{InfoFares: []InfoFare{{
+		FareAdult: Fare{FareAmount: 100},
+		FareChild: Fare{FareAmount: 80},
+	}}}
+	services := []Service{
+		{CodeType: AdminFareCode, Amount: 10, Taxes: []Tax{{TaxAmount: 2}}},
+		{CodeType: "CLAS", Description: "Tarifa Classic", Amount: 40, Taxes: []Tax{{TaxAmount: 5}}},
+		{CodeType: "CLUB", Description: "Wingo Club", Amount: 70},
+	}
+
+	tests := []struct {
+		name       string
+		bundle     string
+		services   []Service
+		passengers Passengers
+		expected   float64
+		available  bool
+	}{
+		{name: "basic", bundle: BundleBasic, services: services, expected: 112, available: true},
+		{name: "default", bundle: "", services: services, expected: 112, available: true},
+		{name: "classic", bundle: "classic", services: services, expected: 157, available: true},
+		{name: "plus alias", bundle: BundlePlus, services: services, passengers: Passengers{Adults: 1, Children: 1}, expected: 180 + 140 + 12, available: true},
+		{name: "bundle not quoted", bundle: BundleClassic, services: services[:1]},
+		{name: "fare not available", bundle: BundleBasic, services: services, passengers: Passengers{Adults: 1, Infants: 1}},
+		{name: "basic without services", bundle: BundleBasic, expected: 100, available: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, available := GetPassengersBundlePrice(tt.bundle, flight, tt.services, tt.passengers)
+			assert.Equal(t, tt.expected, price)
+			assert.Equal(t, tt.available, available)
+		})
+	}
+}
+
+func TestGetPassengerBundleFare(t *testing.T) {
+	flight := Vuelo{InfoFares: []InfoFare{{
+		FareAdult:  Fare{FareAmount: 100},
+		FareInfant: Fare{FareAmount: 10},
+	}}}
+	services := []Service{{CodeType: "CLAS", Amount: 40}}
+
+	price, available := GetPassengerBundleFare(BundleClassic, flight, services, Passengers{Adults: 1})
+	assert.True(t, available)
+	assert.Equal(t, 140.0, price)
+
+	price, available = GetPassengerBundleFare(BundleClassic, flight, services, Passengers{Infants: 1})
+	assert.True(t, available)
+	assert.Equal(t, 10.0, price, "infants do not pay the bundle")
+
+	_, available = GetPassengerBundleFare(BundleClassic, flight, services, Passengers{Children: 1})
+	assert.False(t, available)
+	_, available = GetPassengerBundleFare(BundlePlus, flight, services, Passengers{Adults: 1})
+	assert.False(t, available)
+}
+
+func TestGetBundlePriceOverlappingDescriptions(t *testing.T) {
+	flight := Vuelo{InfoFares: []InfoFare{{FareAdult: Fare{FareAmount: 100}}}}
+	services := []Service{
+		{CodeType: "CLUB", Description: "Classic + Club", Amount: 70},
+		{CodeType: "CLAS", Description: "Classic (antes Club)", Amount: 40},
+		{CodeType: "BAG1", Description: "Equipaje Classic", Amount: 5},
+	}
+
+	price, available := GetBundlePrice(BundleClassic, flight, services)
+	assert.True(t, available)
+	assert.Equal(t, 140.0, price)
+	price, available = GetBundlePrice(BundlePlus, flight, services)
+	assert.True(t, available)
+	assert.Equal(t, 170.0, price)
+	_, available = GetBundlePrice(BundlePlus, flight, services[1:])
+	assert.False(t, available)
+}
+
+func TestNormalizeBundle(t *testing.T) {
+	assert.Equal(t, BundleBasic, NormalizeBundle(""))
+	assert.Equal(t, BundleClassic, NormalizeBundle(" classic "))
+	assert.Equal(t, BundlePlus, NormalizeBundle("club"))
+	assert.True(t, IsBundle("plus"))
+	assert.False(t, IsBundle("gold"))
+}
@@ -2,8 +2,8 @@ package wingo
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -13,12 +13,11 @@ import (
 	"net/url"
 	"os"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
-	"github.com/cenkalti/backoff/v4"
+	"github.com/fabianMendez/wingo/pkg/currency"
 	"github.com/fabianMendez/wingo/pkg/date"
-	"golang.org/x/net/proxy"
 )
 
 const (
@@ -27,56 +26,73 @@ const (
 )
 
 type Client struct {
-	httpClient        *http.Client
-	log               *log.Logger
-	aditionalHeaders  map[string]string
-	RequestCount      int
-	requestCountMutex *sync.Mutex
+	httpClient       *http.Client
+	log              *log.Logger
+	aditionalHeaders map[string]string
+	retryPolicy      RetryPolicy
+	routesURL        string
+	ancillariesURL   string
+	limiter          *limiter
+	metrics          *metrics
 }
 
-func NewClient(logger *log.Logger) *Client {
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	transport.DialContext = proxy.Dial
-	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	httpClient := &http.Client{Transport: transport, Timeout: time.Second * 15}
+func NewClient(opts ...Option) *Client {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
 
 	return &Client{
-		httpClient:        httpClient,
-		requestCountMutex: new(sync.Mutex),
-		log:               logger,
-		aditionalHeaders: map[string]string{
-			"User-Agent":      "Mozilla/5.0 (X11; Linux x86_64; rv:90.0) Gecko/20100101 Firefox/90.0",
-			"Origin":          "https://booking.wingo.com",
-			"Referer":         "https://booking.wingo.com/",
-			"Accept-Language": "en-US,en;q=0.5",
-		},
+		httpClient:       o.buildHTTPClient(),
+		metrics:          newMetrics(),
+		log:              o.logger,
+		retryPolicy:      o.retryPolicy,
+		aditionalHeaders: o.headers,
+		routesURL:        strings.TrimSuffix(o.routesURL, "/"),
+		ancillariesURL:   strings.TrimSuffix(o.ancillariesURL, "/"),
+		limiter:          newLimiter(o.rateLimit, o.rateBurst, o.maxInFlight),
 	}
 }
 
-func (c *Client) request(method, u string, body io.Reader, headers map[string]string) (*http.Response, error) {
-	c.requestCountMutex.Lock()
-	c.RequestCount++
-	c.requestCountMutex.Unlock()
+func endpointName(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	return parsed.Host + parsed.Path
+}
+
+func (c *Client) Metrics() MetricsSnapshot {
+	return c.metrics.snapshot()
+}
+
+func (c *Client) request(ctx context.Context, method, u string, body []byte, headers map[string]string) (*http.Response, error) {
+	endpoint := endpointName(u)
+	c.metrics.request(endpoint)
+
+	fail := func(err error) (*http.Response, error) {
+		c.metrics.failure(endpoint)
+		return nil, err
+	}
 
 	c.log.Println(method, u)
 
-	maxRetries := 10
-	boff := backoff.NewExponentialBackOff()
-	boff.InitialInterval = 5 * time.Second
-	boff.Reset()
+	policy := c.retryPolicy
+	boff := policy.backOff()
 
-	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			c.metrics.retry(endpoint)
+		}
 
-	for i := 1; i <= maxRetries; i++ {
-		if i > 1 {
-			boffDuration := boff.NextBackOff()
-			c.log.Println("*** Backoff retry", i, ":", boffDuration)
-			time.Sleep(boffDuration)
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
 		}
 
-		req, err := http.NewRequest(method, u, body)
+		req, err := http.NewRequestWithContext(ctx, method, u, bodyReader)
 		if err != nil {
-			return nil, fmt.Errorf("could not create request: %w", err)
+			return fail(fmt.Errorf("could not create request: %w", err))
 		}
 
 		for headerKey, headerValue := range c.aditionalHeaders {
@@ -87,31 +103,61 @@ func (c *Client) request(method, u string, body io.Reader, headers map[string]st
 			req.Header.Add(headerKey, headerValue)
 		}
 
-		resp, err = c.httpClient.Do(req)
+		var retryAfter time.Duration
+
+		release, err := c.limiter.acquire(ctx, req.URL.Host)
 		if err != nil {
-			if i != maxRetries {
-				fmt.Fprintf(os.Stderr, "retry %d: %v\n", i, err)
-				continue
-			}
-			return nil, fmt.Errorf("could not send request: %w", err)
+			return fail(fmt.Errorf("could not send request: %w", err))
 		}
 
-		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
-			if i != maxRetries {
-				fmt.Fprintf(os.Stderr, "retry %d: %v\n", i, resp.StatusCode)
-				continue
+		start := time.Now()
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			c.metrics.attempt(endpoint, 0, time.Since(start))
+			release()
+			if ctx.Err() != nil || attempt >= policy.MaxAttempts {
+				return fail(fmt.Errorf("could not send request: %w", err))
+			}
+			fmt.Fprintf(os.Stderr, "retry %d: %v\n", attempt, err)
+		} else if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotModified {
+			c.metrics.attempt(endpoint, resp.StatusCode, time.Since(start))
+			resp.Body = releaseOnClose{
+				ReadCloser: meteredBody{ReadCloser: resp.Body, metrics: c.metrics, endpoint: endpoint},
+				release:    release,
+			}
+			return resp, nil
+		} else {
+			c.metrics.attempt(endpoint, resp.StatusCode, time.Since(start))
+			resp.Body.Close()
+			release()
+			if !policy.isRetryable(resp.StatusCode) || attempt >= policy.MaxAttempts {
+				return fail(fmt.Errorf("request failed: %s %s - %s", method, u, resp.Status))
+			}
+			fmt.Fprintf(os.Stderr, "retry %d: %v\n", attempt, resp.StatusCode)
+
+			if policy.RespectRetryAfter {
+				retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
 			}
-			return nil, fmt.Errorf("request failed: %s %s - %s", method, u, resp.Status)
 		}
 
-		break
+		boffDuration := boff.NextBackOff()
+		if retryAfter > boffDuration {
+			boffDuration = retryAfter
+		}
+		c.log.Println("*** Backoff retry", attempt+1, ":", boffDuration)
+
+		timer := time.NewTimer(boffDuration)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fail(fmt.Errorf("could not send request: %w", ctx.Err()))
+		case <-timer.C:
+		}
 	}
-
-	return resp, nil
 }
 
-func (c *Client) requestJSON(method, u string, body io.Reader, v interface{}, headers map[string]string) error {
-	resp, err := c.request(method, u, body, headers)
+func (c *Client) requestJSON(ctx context.Context, method, u string, body []byte, v interface{}, headers map[string]string) error {
+	resp, err := c.request(ctx, method, u, body, headers)
 	if err != nil {
 		return err
 	}
@@ -125,7 +171,7 @@ func (c *Client) requestJSON(method, u string, body io.Reader, v interface{}, he
 	return nil
 }
 
-func (c *Client) requestJSONWithCache(method, u string, body io.Reader, v interface{}, path string, headers map[string]string) error {
+func (c *Client) requestJSONWithCache(ctx context.Context, method, u string, body []byte, v interface{}, path string, headers map[string]string) error {
 	content, err := os.ReadFile(path)
 	if err != nil && !os.IsNotExist(err) {
 		return err
@@ -139,7 +185,7 @@ func (c *Client) requestJSONWithCache(method, u string, body io.Reader, v interf
 		headers["If-None-Match"] = etag
 	}
 
-	resp, err := c.request(method, u, body, headers)
+	resp, err := c.request(ctx, method, u, body, headers)
 	if err != nil {
 		return err
 	}
@@ -168,31 +214,70 @@ func (c *Client) requestJSONWithCache(method, u string, body io.Reader, v interf
 	return nil
 }
 
-func (c *Client) GetInformationFlightsMonthly(origin, destination, startDate string, daysAfter int) (FlightsInformation, error) {
-	startTime, err := date.Parse(startDate)
+const (
+	flightTypeOneWay    = "1"
+	flightTypeRoundTrip = "2"
+)
+
+// FlightSearch describes the flights requested to SearchFlights. When
+// ReturnStartDate is set the search is for round trips and the return flights
+// are decoded in FlightsInformation.VueloRegreso.
+type FlightSearch struct {
+	Origin          string
+	Destination     string
+	StartDate       string
+	DaysAfter       int
+	ReturnStartDate string
+	ReturnDaysAfter int
+	Passengers      Passengers
+	// Currency of the fares, currency.Default when empty.
+	Currency string
+}
+
+func (s FlightSearch) IsRoundTrip() bool {
+	return s.ReturnStartDate != ""
+}
+
+func (c *Client) SearchFlights(ctx context.Context, search FlightSearch) (FlightsInformation, error) {
+	startTime, err := date.Parse(search.StartDate)
 	if err != nil {
 		return FlightsInformation{}, err
 	}
 
-	endTime := startTime.AddDate(0, 0, daysAfter)
+	endTime := startTime.AddDate(0, 0, search.DaysAfter)
 	originEndDate := date.Format(endTime)
 
 	parameters := url.Values{}
-	parameters.Set("origin", origin)
-	parameters.Set("originStartDate", startDate)
+	parameters.Set("origin", search.Origin)
+	parameters.Set("originStartDate", search.StartDate)
 	parameters.Set("originEndDate", originEndDate)
 	parameters.Set("originDaysBefore", "0")
-	parameters.Set("originDaysAfter", strconv.Itoa(daysAfter))
-	parameters.Set("destination", destination)
-	parameters.Set("destinationStartDate", "Fecha inválida")
-	parameters.Set("destinationEndDate", "Fecha inválida")
-	parameters.Set("destinationDaysBefore", "0")
-	parameters.Set("destinationDaysAfter", "NaN")
-	parameters.Set("currency", "COP")
-	parameters.Set("adultNumber", "1")
-	parameters.Set("childNumber", "0")
-	parameters.Set("infantNumber", "0")
-	parameters.Set("flightType", "1")
+	parameters.Set("originDaysAfter", strconv.Itoa(search.DaysAfter))
+	parameters.Set("destination", search.Destination)
+	if search.IsRoundTrip() {
+		returnStartTime, err := date.Parse(search.ReturnStartDate)
+		if err != nil {
+			return FlightsInformation{}, err
+		}
+
+		returnEndTime := returnStartTime.AddDate(0, 0, search.ReturnDaysAfter)
+		parameters.Set("destinationStartDate", search.ReturnStartDate)
+		parameters.Set("destinationEndDate", date.Format(returnEndTime))
+		parameters.Set("destinationDaysBefore", "0")
+		parameters.Set("destinationDaysAfter", strconv.Itoa(search.ReturnDaysAfter))
+		parameters.Set("flightType", flightTypeRoundTrip)
+	} else {
+		parameters.Set("destinationStartDate", "Fecha inválida")
+		parameters.Set("destinationEndDate", "Fecha inválida")
+		parameters.Set("destinationDaysBefore", "0")
+		parameters.Set("destinationDaysAfter", "NaN")
+		parameters.Set("flightType", flightTypeOneWay)
+	}
+	parameters.Set("currency", currency.Normalize(search.Currency))
+	passengers := search.Passengers.OrDefault()
+	parameters.Set("adultNumber", strconv.Itoa(passengers.Adults))
+	parameters.Set("childNumber", strconv.Itoa(passengers.Children))
+	parameters.Set("infantNumber", strconv.Itoa(passengers.Infants))
 	parameters.Set("securityToken", "")
 	parameters.Set("iataNumber", "")
 	parameters.Set("userAgent", "IBE")
@@ -201,13 +286,13 @@ func (c *Client) GetInformationFlightsMonthly(origin, destination, startDate str
 	parameters.Set("multiCurrency", "false")
 	parameters.Set("languageId", "1")
 
-	u := "https://routes-api.wingo.com/v1/getInformationFlightsMonthly?" + parameters.Encode()
+	u := c.routesURL + "/v1/getInformationFlightsMonthly?" + parameters.Encode()
 
 	var response struct {
 		Response FlightsInformation `json:"response"`
 	}
 
-	err = c.requestJSON(http.MethodGet, u, nil, &response, nil)
+	err = c.requestJSON(ctx, http.MethodGet, u, nil, &response, nil)
 	if err != nil {
 		return FlightsInformation{}, err
 	}
@@ -215,8 +300,29 @@ func (c *Client) GetInformationFlightsMonthly(origin, destination, startDate str
 	return response.Response, nil
 }
 
-func (c *Client) RetrieveServiceQuotes(flights []FlightService, token string) ([]ServiceQuote, error) {
-	u := "https://ancillaries-api.wingo.com/v1/retrieveServiceQuotes"
+func (c *Client) GetInformationFlightsMonthly(ctx context.Context, origin, destination, startDate string, daysAfter int) (FlightsInformation, error) {
+	return c.SearchFlights(ctx, FlightSearch{
+		Origin:      origin,
+		Destination: destination,
+		StartDate:   startDate,
+		DaysAfter:   daysAfter,
+	})
+}
+
+func (c *Client) GetInformationFlightsRoundTrip(ctx context.Context, origin, destination, startDate string, daysAfter int,
+	returnStartDate string, returnDaysAfter int) (FlightsInformation, error) {
+	return c.SearchFlights(ctx, FlightSearch{
+		Origin:          origin,
+		Destination:     destination,
+		StartDate:       startDate,
+		DaysAfter:       daysAfter,
+		ReturnStartDate: returnStartDate,
+		ReturnDaysAfter: returnDaysAfter,
+	})
+}
+
+func (c *Client) RetrieveServiceQuotes(ctx context.Context, flights []FlightService, token, cur string) ([]ServiceQuote, error) {
+	u := c.ancillariesURL + "/v1/retrieveServiceQuotes"
 
 	rb := struct {
 		Currency      string          `json:"currency"`
@@ -226,7 +332,7 @@ func (c *Client) RetrieveServiceQuotes(flights []FlightService, token string) ([
 		Multicurrency bool            `json:"multicurrency"`
 		LanguageID    int64           `json:"languageId"`
 	}{
-		Currency:      "COP",
+		Currency:      currency.Normalize(cur),
 		Token:         token,
 		Module:        "2",
 		Multicurrency: false,
@@ -245,7 +351,7 @@ func (c *Client) RetrieveServiceQuotes(flights []FlightService, token string) ([
 
 	headers := map[string]string{"Content-Type": "application/json"}
 	fmt.Println(string(bodyBytes))
-	err = c.requestJSON(http.MethodPost, u, bytes.NewReader(bodyBytes), &response, headers)
+	err = c.requestJSON(ctx, http.MethodPost, u, bodyBytes, &response, headers)
 	if err != nil {
 		return nil, err
 	}
@@ -253,49 +359,72 @@ func (c *Client) RetrieveServiceQuotes(flights []FlightService, token string) ([
 	return response.Response, nil
 }
 
+// CleanFlightNumber returns the number of a flight without the airline code,
+// so that "P5-7013", "p57013" and "7013" are the same flight.
+func CleanFlightNumber(flightNumber string) string {
+	flightNumber = strings.ToUpper(strings.TrimSpace(flightNumber))
+	if i := strings.Index(flightNumber, "-"); i != -1 {
+		flightNumber = flightNumber[i+1:]
+	}
+	return strings.TrimPrefix(flightNumber, "P5")
+}
+
 func SumarPrecioCalendario(flight Vuelo) float64 {
-	if len(flight.InfoFares) > 0 {
-		var taxes float64
-		fare := flight.InfoFares[0].FareAdult
-		for _, tax := range fare.ApplicableTaxes {
-			taxes += tax.TaxAmount
+	price, _ := SumarPrecioPasajeros(flight, SingleAdult)
+	return price
+}
+
+func cheapestFlight(vuelos []VueloIda, fecha string, passengers Passengers) (Vuelo, float64, bool) {
+	var cheapest Vuelo
+	var cheapestPrice float64
+	found := false
+
+	for _, vueloIda := range vuelos {
+		if vueloIda.Fecha != fecha {
+			continue
+		}
+
+		for _, vuelo := range vueloIda.InfoVuelo.Vuelos {
+			price, available := SumarPrecioPasajeros(vuelo, passengers)
+			if available && (!found || price < cheapestPrice) {
+				cheapest, cheapestPrice, found = vuelo, price, true
+			}
 		}
-		return fare.FareAmount + taxes
 	}
-	return 0
+
+	return cheapest, cheapestPrice, found
 }
 
-func GetBundlePrice(bundle string, flight Vuelo, adminFares float64) float64 {
-	var bundlePrice float64
-	// if "BASIC" != bundle {
-	// 	panic("bundlePrice = getBundlePriceByTitle(bundle)")
-	// }
-	return SumarPrecioCalendario(flight) + adminFares + bundlePrice
+// CheapestRoundTrip returns the cheapest combination of an outbound flight on
+// date and a return flight on returnDate, and the sum of their fares for the
+// given passengers. The admin fares of each leg are not included.
+func CheapestRoundTrip(information FlightsInformation, date, returnDate string, passengers Passengers) (outbound, inbound Vuelo, price float64, found bool) {
+	outbound, outboundPrice, outboundFound := cheapestFlight(information.VueloIda, date, passengers)
+	inbound, inboundPrice, inboundFound := cheapestFlight(information.VueloRegreso, returnDate, passengers)
+	if !outboundFound || !inboundFound {
+		return Vuelo{}, Vuelo{}, 0, false
+	}
+
+	return outbound, inbound, outboundPrice + inboundPrice, true
 }
 
 func GetAdminFares(serviceQuote ServiceQuote) float64 {
 	for _, service := range serviceQuote.Services {
 		if service.CodeType == AdminFareCode {
-			var taxes float64
-
-			for _, tax := range service.Taxes {
-				taxes += tax.TaxAmount
-			}
-
-			return service.Amount + taxes
+			return sumarServicio(service)
 		}
 	}
 	return 0
 }
 
-func (c *Client) GetRoutes() ([]Route, error) {
-	u := "https://routes-api.wingo.com/v1/completeroute/es"
+func (c *Client) GetRoutes(ctx context.Context) ([]Route, error) {
+	u := c.routesURL + "/v1/completeroute/es"
 
 	var response struct {
 		Response []Route `json:"response"`
 	}
 
-	err := c.requestJSON(http.MethodGet, u, nil, &response, nil)
+	err := c.requestJSON(ctx, http.MethodGet, u, nil, &response, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -315,14 +444,14 @@ func wetag(buf []byte) string {
 	return fmt.Sprintf(`W/"%x-%s"`, len(buf), hash[0:27])
 }
 
-func (c *Client) GetRoutesWithCache(path string) ([]Route, error) {
-	u := "https://routes-api.wingo.com/v1/completeroute/es"
+func (c *Client) GetRoutesWithCache(ctx context.Context, path string) ([]Route, error) {
+	u := c.routesURL + "/v1/completeroute/es"
 
 	var response struct {
 		Response []Route `json:"response"`
 	}
 
-	err := c.requestJSONWithCache(http.MethodGet, u, nil, &response, path, nil)
+	err := c.requestJSONWithCache(ctx, http.MethodGet, u, nil, &response, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -332,7 +461,7 @@ func (c *Client) GetRoutesWithCache(path string) ([]Route, error) {
 
 // now
 // now + 10 months
-func (c *Client) GetFlightScheduleInformation(origin, destination, startDate, endDate string) (FlightScheduleInformation, error) {
+func (c *Client) GetFlightScheduleInformation(ctx context.Context, origin, destination, startDate, endDate string) (FlightScheduleInformation, error) {
 	parameters := url.Values{}
 	parameters.Set("carrierCode", "P5")
 	parameters.Set("searchType", "")
@@ -342,12 +471,12 @@ func (c *Client) GetFlightScheduleInformation(origin, destination, startDate, en
 	parameters.Set("endDate", endDate)
 	parameters.Set("flightNumber", "0")
 	parameters.Set("includedCancelled", "false")
-	u := "https://routes-api.wingo.com/v1/scheduleinformation?" + parameters.Encode()
+	u := c.routesURL + "/v1/scheduleinformation?" + parameters.Encode()
 
 	var response struct {
 		Response FlightScheduleInformation `json:"response"`
 	}
 
-	err := c.requestJSON(http.MethodGet, u, nil, &response, nil)
+	err := c.requestJSON(ctx, http.MethodGet, u, nil, &response, nil)
 	return response.Response, err
 }
@@ -0,0 +1,271 @@
+package wingo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fabianMendez/wingo/internal/testenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 3
+	policy.InitialInterval = time.Millisecond
+	policy.MaxInterval = time.Millisecond
+	return policy
+}
+
+func TestRequestRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		expectedAttempts int
+		expectErr        bool
+	}{
+		{
+			name:             "retries server errors",
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "does not retry client errors",
+			statuses:         []int{http.StatusBadRequest, http.StatusOK},
+			expectedAttempts: 1,
+			expectErr:        true,
+		},
+		{
+			name:             "gives up after max attempts",
+			statuses:         []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			expectedAttempts: 3,
+			expectErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, "payload", string(body))
+				w.WriteHeader(tt.statuses[attempts])
+				attempts++
+			}))
+			defer server.Close()
+
+			client := NewClient(WithLogger(log.New(io.Discard, "", 0)), WithRetryPolicy(testRetryPolicy()))
+			resp, err := client.request(context.Background(), http.MethodPost, server.URL, []byte("payload"), nil)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				resp.Body.Close()
+			}
+			assert.Equal(t, tt.expectedAttempts, attempts)
+		})
+	}
+}
+
+func TestRequestCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := NewClient(WithLogger(log.New(io.Discard, "", 0)), WithRetryPolicy(testRetryPolicy()))
+	_, err := client.request(ctx, http.MethodGet, server.URL, nil, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter("Sat, 01 Jan 2022 00:00:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func TestCleanFlightNumber(t *testing.T) {
+	assert.Equal(t, "7013", CleanFlightNumber("P5-7013"))
+	assert.Equal(t, "7013", CleanFlightNumber(" p57013 "))
+	assert.Equal(t, "7013", CleanFlightNumber("7013"))
+}
+
+func TestClientOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/completeroute/es", r.URL.Path)
+		assert.Equal(t, "wingo-test", r.Header.Get("User-Agent"))
+		assert.Equal(t, "https://booking.wingo.com", r.Header.Get("Origin"))
+		_, _ = io.WriteString(w, `{"response":[{"code":"BOG","routes":[{"code":"CTG"}]}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithHTTPClient(server.Client()),
+		WithRoutesURL(server.URL+"/"),
+		WithHeader("User-Agent", "wingo-test"),
+	)
+
+	routes, err := client.GetRoutes(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Route{{Code: "BOG", Routes: []Route{{Code: "CTG"}}}}, routes)
+}
+
+func TestTLSOptions(t *testing.T) {
+	o := defaultOptions()
+	assert.False(t, o.tlsConfig.InsecureSkipVerify, "certificates are verified by default")
+
+	for _, opt := range []Option{WithTLSConfig(nil), WithTLSVerification(false)} {
+		opt(&o)
+	}
+	require.NotNil(t, o.tlsConfig)
+	assert.True(t, o.tlsConfig.InsecureSkipVerify)
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	for _, key := range []string{"WINGO_RATE_LIMIT", "WINGO_RATE_BURST", "WINGO_MAX_IN_FLIGHT"} {
+		testenv.Setenv(t, key, "")
+	}
+
+	o := defaultOptions()
+	for _, opt := range OptionsFromEnv() {
+		opt(&o)
+	}
+	assert.Equal(t, DefaultRateLimit, o.rateLimit, "requests are limited by default")
+	assert.Equal(t, DefaultRateBurst, o.rateBurst)
+	assert.Equal(t, DefaultMaxInFlight, o.maxInFlight)
+
+	testenv.Setenv(t, "WINGO_RATE_LIMIT", "0")
+	testenv.Setenv(t, "WINGO_MAX_IN_FLIGHT", "10")
+	for _, opt := range OptionsFromEnv() {
+		opt(&o)
+	}
+	assert.Equal(t, 0.0, o.rateLimit)
+	assert.Equal(t, 10, o.maxInFlight)
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+		_, _ = io.WriteString(w, `{"response":[]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithRoutesURL(server.URL),
+		WithMaxInFlight(2),
+		WithRateLimit(1000, 10),
+	)
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetRoutes(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxInFlight, 2)
+}
+
+func TestMetrics(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{"response":[]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithLogger(log.New(io.Discard, "", 0)),
+		WithRoutesURL(server.URL),
+		WithRetryPolicy(testRetryPolicy()),
+	)
+
+	_, err := client.GetRoutes(context.Background())
+	require.NoError(t, err)
+
+	metrics := client.Metrics()
+	endpoint := strings.TrimPrefix(server.URL, "http://") + "/v1/completeroute/es"
+	require.Contains(t, metrics.Endpoints, endpoint)
+	m := metrics.Endpoints[endpoint]
+	assert.Equal(t, 1, m.Requests)
+	assert.Equal(t, 1, m.Retries)
+	assert.Equal(t, 0, m.Failures)
+	assert.Equal(t, map[int]int{http.StatusServiceUnavailable: 1, http.StatusOK: 1}, m.StatusCodes)
+	assert.Equal(t, int64(len(`{"response":[]}`)), m.Bytes)
+
+	buf := new(strings.Builder)
+	require.NoError(t, metrics.WritePrometheus(buf))
+	assert.Contains(t, buf.String(), fmt.Sprintf("wingo_requests_total{endpoint=%q} 1\n", endpoint))
+	assert.Contains(t, buf.String(), fmt.Sprintf("wingo_responses_total{endpoint=%q,code=\"503\"} 1\n", endpoint))
+}
+
+func TestGetInformationFlightsRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "2", query.Get("flightType"))
+		assert.Equal(t, "2022-01-10", query.Get("destinationStartDate"))
+		assert.Equal(t, "2022-01-12", query.Get("destinationEndDate"))
+		assert.Equal(t, "2", query.Get("destinationDaysAfter"))
+		_, _ = io.WriteString(w, `{"response":{
+			"vueloIda":[{"fecha":"2022-01-01","infoVuelo":{"vuelos":[
+				{"flightNumber":"7013","infoFares":[{"fareAdult":{"fareAmount":100}}]},
+				{"flightNumber":"7015","infoFares":[{"fareAdult":{"fareAmount":80}}]}]}}],
+			"vueloRegreso":[{"fecha":"2022-01-10","infoVuelo":{"vuelos":[
+				{"flightNumber":"7014","infoFares":[{"fareAdult":{"fareAmount":50,"applicableTaxes":[{"taxAmount":5}]}}]}]}}]}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithLogger(log.New(io.Discard, "", 0)), WithRoutesURL(server.URL))
+
+	information, err := client.GetInformationFlightsRoundTrip(context.Background(), "BOG", "CTG", "2022-01-01", 0, "2022-01-10", 2)
+	require.NoError(t, err)
+	require.Len(t, information.VueloRegreso, 1)
+
+	ida, regreso, price, found := CheapestRoundTrip(information, "2022-01-01", "2022-01-10", SingleAdult)
+	assert.True(t, found)
+	assert.Equal(t, "7015", ida.FlightNumber)
+	assert.Equal(t, "7014", regreso.FlightNumber)
+	assert.Equal(t, float64(135), price)
+
+	_, _, _, found = CheapestRoundTrip(information, "2022-01-01", "2022-01-11", SingleAdult)
+	assert.False(t, found)
+}
@@ -2,19 +2,47 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"html"
 	"log"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/fabianMendez/wingo/pkg/i18n"
 	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/storage"
 )
 
+// requestLanguage returns the language asked by the lang parameter or the
+// Accept-Language header of the request.
+func requestLanguage(request events.APIGatewayProxyRequest) string {
+	if lang := request.QueryStringParameters["lang"]; lang != "" {
+		return i18n.Normalize(lang)
+	}
+	if header := request.Headers["accept-language"]; header != "" {
+		return i18n.FromAcceptLanguage(header)
+	}
+	return i18n.FromAcceptLanguage(request.Headers["Accept-Language"])
+}
+
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
 	uid := request.QueryStringParameters["uid"]
+	lang := requestLanguage(request)
 
 	if uid != "" {
-		err := notifications.DeleteSetting(uid)
+		backend, err := storage.NewFromEnv(storage.KindGithub)
+		if err != nil {
+			return nil, err
+		}
+		defer storage.Close(backend)
+
+		setting, err := notifications.GetSetting(backend, uid)
+		if err == nil {
+			lang = setting.GetLanguage()
+		}
+
+		err = notifications.DeleteSetting(backend, uid)
 		if err != nil {
 			log.Println(err)
 		}
@@ -25,9 +53,9 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*event
 		Headers: map[string]string{
 			"Content-Type": "text/html; charset=utf-8",
 		},
-		Body: `
+		Body: fmt.Sprintf(`
 <!DOCTYPE html>
-<html lang="es">
+<html lang="%s">
 <head>
     <meta charset="UTF-8">
     <meta http-equiv="X-UA-Compatible" content="IE=edge">
@@ -35,10 +63,10 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*event
     <title>Document</title>
 </head>
 <body>
-	<h1>La suscripción ha sido cancelada</h1>
+	<h1>%s</h1>
 </body>
 </html>
-		`,
+		`, lang, html.EscapeString(i18n.T(lang, "subscription.cancelled"))),
 	}, nil
 }
 
@@ -2,27 +2,38 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"html"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/fabianMendez/wingo/pkg/i18n"
 	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/storage"
 )
 
-func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
-	var setting notifications.Setting
-	uid := request.QueryStringParameters["uid"]
+// requestLanguage returns the language asked by the lang parameter or the
+// Accept-Language header of the request.
+func requestLanguage(request events.APIGatewayProxyRequest) string {
+	if lang := request.QueryStringParameters["lang"]; lang != "" {
+		return i18n.Normalize(lang)
+	}
+	if header := request.Headers["accept-language"]; header != "" {
+		return i18n.FromAcceptLanguage(header)
+	}
+	return i18n.FromAcceptLanguage(request.Headers["Accept-Language"])
+}
 
-	setting, err := notifications.GetSetting(uid)
-	if err != nil {
-		return &events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Headers: map[string]string{
-				"Content-Type": "text/html; charset=utf-8",
-			},
-			Body: `
+func page(statusCode int, lang, key string) *events.APIGatewayProxyResponse {
+	return &events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type": "text/html; charset=utf-8",
+		},
+		Body: fmt.Sprintf(`
 <!DOCTYPE html>
-<html lang="es">
+<html lang="%s">
 <head>
     <meta charset="UTF-8">
     <meta http-equiv="X-UA-Compatible" content="IE=edge">
@@ -30,41 +41,37 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*event
     <title>Document</title>
 </head>
 <body>
-	<h1>Suscripción no encontrada</h1>
+	<h1>%s</h1>
 </body>
 </html>
-		`,
-		}, nil
+		`, lang, html.EscapeString(i18n.T(lang, key))),
+	}
+}
+
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	var setting notifications.Setting
+	uid := request.QueryStringParameters["uid"]
+
+	backend, err := storage.NewFromEnv(storage.KindGithub)
+	if err != nil {
+		return nil, err
+	}
+	defer storage.Close(backend)
+
+	setting, err = notifications.GetSetting(backend, uid)
+	if err != nil {
+		return page(http.StatusBadRequest, requestLanguage(request), "subscription.not_found"), nil
 	}
 
 	if !setting.Confirmed {
 		setting.Confirmed = true
-		err = notifications.UpdateSetting(uid, setting)
+		err = notifications.UpdateSetting(backend, uid, setting)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return &events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Headers: map[string]string{
-			"Content-Type": "text/html; charset=utf-8",
-		},
-		Body: `
-<!DOCTYPE html>
-<html lang="es">
-<head>
-    <meta charset="UTF-8">
-    <meta http-equiv="X-UA-Compatible" content="IE=edge">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Document</title>
-</head>
-<body>
-	<h1>La suscripción ha sido confirmada</h1>
-</body>
-</html>
-		`,
-	}, nil
+	return page(http.StatusOK, setting.GetLanguage(), "subscription.confirmed"), nil
 }
 
 func main() {
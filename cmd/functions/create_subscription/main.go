@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,8 +11,13 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/fabianMendez/wingo"
+	"github.com/fabianMendez/wingo/pkg/date"
 	"github.com/fabianMendez/wingo/pkg/email"
+	"github.com/fabianMendez/wingo/pkg/i18n"
 	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/notify"
+	"github.com/fabianMendez/wingo/pkg/storage"
 	"github.com/fabianMendez/wingo/pkg/whatsapp"
 )
 
@@ -25,51 +31,214 @@ var headers = map[string]string{
 	"Content-Type":                 "application/json",
 }
 
-func createSubscription(ctx context.Context, body []byte) error {
+// validateFlightNumber checks that the flight of the subscription is
+// scheduled on any of its routes and dates.
+func validateFlightNumber(ctx context.Context, client *wingo.Client, setting notifications.Setting) error {
+	start, end, err := setting.DateRange()
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range setting.RoutePairs() {
+		information, err := client.GetFlightScheduleInformation(ctx, pair.Origin, pair.Destination, date.Format(start), date.Format(end))
+		if err != nil {
+			return fmt.Errorf("could not get flight schedule: %w", err)
+		}
+
+		for _, flight := range information.FlightInformation {
+			if setting.MatchesFlight(flight.FlightNumber) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("flight %s is not scheduled from %s to %s on %s",
+		setting.FlightNumber, setting.Origin, setting.Destination, setting.DateLabel())
+}
+
+func containsChannel(channels []string, channel string) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmationChannels are the channels whose address is owned by the
+// subscriber, so that the confirmation link proves it. The webhook is left
+// out, as its URL can point anywhere.
+var confirmationChannels = []string{notify.ChannelEmail, notify.ChannelWhatsApp, notify.ChannelTelegram}
+
+// sendConfirmation sends event through every confirmation channel with an
+// address for recipient, and fails unless one of them delivered it.
+func sendConfirmation(ctx context.Context, registry *notify.Registry, recipient notify.Recipient, event notify.Event) error {
+	delivered := false
+	var firstErr error
+	for _, name := range confirmationChannels {
+		channel, found := registry.Channel(name)
+		if !found {
+			continue
+		}
+		if addresser, ok := channel.(notify.Addresser); ok && !addresser.HasAddress(recipient) {
+			continue
+		}
+
+		err := channel.Send(ctx, recipient, event)
+		if err != nil {
+			log.Printf("could not send confirmation through %s: %v", name, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("could not send confirmation: %s: %w", name, err)
+			}
+			continue
+		}
+		delivered = true
+	}
+
+	if !delivered {
+		if firstErr != nil {
+			return firstErr
+		}
+		return errors.New("no channel can deliver the confirmation: an email, phone number or telegram chat is required")
+	}
+	return nil
+}
+
+// createResponse does not include the UID of the subscription, which
+// confirms and cancels it.
+type createResponse struct {
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+}
+
+func createSubscription(ctx context.Context, body []byte) (*createResponse, error) {
 	var setting notifications.Setting
 	err := json.Unmarshal(body, &setting)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	setting.Confirmed = false
-	uid, err := notifications.SaveSetting(setting)
+	err = setting.Validate()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	link := baseURL + "/.netlify/functions/confirm_subscription?uid=" + uid
-	if len(setting.Email) != 0 {
-		// `Please confirm your subscription`
-		err = email.SendMessage(ctx, `Por favor confirma tu suscripción`, email.TplConfirmSubscription, map[string]interface{}{
-			"subscription": setting,
-			"link":         link,
-		}, setting.Email)
+	if !wingo.IsBundle(setting.Bundle) {
+		return nil, fmt.Errorf("unknown bundle: %s", setting.Bundle)
+	}
+
+	if setting.PhoneNumber != "" {
+		countryCode := os.Getenv("WA_DEFAULT_COUNTRY_CODE")
+		if countryCode == "" {
+			countryCode = whatsapp.DefaultCountryCode
+		}
+
+		setting.PhoneNumber, err = whatsapp.NormalizePhoneNumber(setting.PhoneNumber, countryCode)
 		if err != nil {
-			return fmt.Errorf("could not send email message: %w", err)
+			return nil, err
 		}
+	}
 
-		log.Println("Email message sent")
+	if setting.WebhookURL != "" {
+		err = notify.ValidateWebhookURL(ctx, setting.WebhookURL)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if len(setting.PhoneNumber) != 0 {
-		message := fmt.Sprintf("Usa el siguiente link para confirmar tu suscripción para recibir notificaciones sobre actualizaciones del precio de la ruta %s -> %s el %s:\n\n%s",
-			setting.Origin, setting.Destination, setting.Date, link)
-		err := whatsapp.SendMessage(setting.PhoneNumber, "Por favor confirma tu suscripción", message)
+	client := wingo.NewClient(wingo.OptionsFromEnv()...)
+	if setting.IsMultiAirport() {
+		routes, err := client.GetRoutes(ctx)
 		if err != nil {
-			return fmt.Errorf("could not send whatsapp message: %w", err)
+			return nil, fmt.Errorf("could not get routes: %w", err)
 		}
 
-		log.Println("Whatsapp message sent")
+		setting.Routes, err = setting.ResolveRoutes(routes)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return nil
+	if setting.FlightNumber != "" {
+		err = validateFlightNumber(ctx, client, setting)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	backend, err := storage.NewFromEnv(storage.KindGithub)
+	if err != nil {
+		return nil, err
+	}
+	defer storage.Close(backend)
+
+	registry, err := notify.NewRegistryFromEnv(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registry.Validate(setting.Channels)
+	if err != nil {
+		return nil, err
+	}
+
+	setting.Confirmed = false
+	setting.TelegramToken = ""
+	botName := os.Getenv("TELEGRAM_BOT_NAME")
+	if botName != "" && containsChannel(registry.Resolve(setting.Channels), notify.ChannelTelegram) {
+		setting.TelegramToken, err = notifications.NewTelegramToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	uid, err := notifications.SaveSetting(backend, setting)
+	if err != nil {
+		return nil, err
+	}
+
+	link := baseURL + "/.netlify/functions/confirm_subscription?uid=" + uid
+	lang := setting.GetLanguage()
+	message := i18n.T(lang, "confirm.body", setting.Origin, setting.Destination, setting.DateLabel()) + "\n\n" + link
+	var telegramLink string
+	if setting.TelegramToken != "" {
+		telegramLink = "https://t.me/" + botName + "?start=" + notifications.TelegramStartParameter(uid, setting.TelegramToken)
+		message += "\n\n" + i18n.T(lang, "confirm.telegram") + "\n" + telegramLink
+	}
+	err = sendConfirmation(ctx, registry, setting.Recipient(), notify.Event{
+		Type:            notify.EventConfirmSubscription,
+		SubscriptionUID: uid,
+		Origin:          setting.Origin,
+		Destination:     setting.Destination,
+		Date:            setting.DateLabel(),
+		FlightNumber:    setting.FlightNumber,
+		Language:        lang,
+		Subject:         i18n.T(lang, "confirm.subject"),
+		Message:         message,
+		Link:            link,
+		Template:        email.TplConfirmSubscription,
+		Data: map[string]interface{}{
+			"subscription":  setting,
+			"link":          link,
+			"telegram_link": telegramLink,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Confirmation sent")
+
+	response := &createResponse{}
+	if key := os.Getenv("NOTIFY_WEBHOOK_SIGNING_KEY"); setting.WebhookURL != "" && key != "" {
+		response.WebhookSecret = notify.SubscriptionSecret(key, uid)
+	}
+	return response, nil
 }
 
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
 	log.Println("creating subscription")
 
-	err := createSubscription(ctx, []byte(request.Body))
+	response, err := createSubscription(ctx, []byte(request.Body))
 	if err != nil {
 		log.Println(err)
 		return &events.APIGatewayProxyResponse{
@@ -81,9 +250,15 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*event
 
 	log.Println("subscription successfully created")
 
+	body, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
 	return &events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
 		Headers:    headers,
+		Body:       string(body),
 	}, nil
 }
 
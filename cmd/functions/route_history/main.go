@@ -8,20 +8,15 @@ import (
 	"net/http"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/fabianMendez/bits/syncbits"
 	"github.com/fabianMendez/wingo"
 	"github.com/fabianMendez/wingo/pkg/storage"
 )
 
-const (
-	outdir     = "flights"
-	maxWorkers = 10
-)
+const outdir = "flights"
 
 var headers = map[string]string{
 	"Access-Control-Allow-Origin":  "*",
@@ -39,64 +34,55 @@ type vueloArchivado struct {
 	Services []wingo.Service `json:"services"`
 }
 
-func calculatePrice(vuelo wingo.Vuelo, services []wingo.Service) float64 {
-	adminFares := wingo.GetAdminFares(wingo.ServiceQuote{
-		Services: services,
-	})
-
-	return wingo.GetBundlePrice(wingo.OriginalPlanName, vuelo, adminFares)
+func calculatePrice(vuelo wingo.Vuelo, services []wingo.Service) (float64, bool) {
+	return wingo.GetBundlePrice(wingo.BundleBasic, vuelo, services)
 }
 
 func routeHistory(origin, destination, date, flightNumber string) (map[string]float64, error) {
-	githubStorage, err := storage.NewGithubFromEnv()
+	backend, err := storage.NewFromEnv(storage.KindGithub)
 	if err != nil {
 		return nil, err
 	}
+	defer storage.Close(backend)
 
-	path := fmt.Sprintf("%s/%s/%s/%s/%s.json", outdir, origin, destination, date, flightNumber)
 	twoWeeksAgo := time.Now().AddDate(0, 0, -15)
-	hashes, err := githubStorage.Commits(path, twoWeeksAgo)
+	vuelos := map[string]float64{}
+
+	if historian, ok := backend.(storage.PriceHistorian); ok {
+		prices, err := historian.PriceHistory(origin, destination, date, flightNumber, twoWeeksAgo)
+		if err != nil {
+			log.Println("could not get price history: ", err)
+			return nil, err
+		}
+
+		for observedAt, price := range prices {
+			vuelos[observedAt.UTC().Format(time.RFC3339)] = price
+		}
+		return vuelos, nil
+	}
+
+	path := fmt.Sprintf("%s/%s/%s/%s/%s.json", outdir, origin, destination, date, flightNumber)
+	revisions, err := backend.History(path, twoWeeksAgo)
 	if err != nil {
-		log.Println("could not get commits: ", err)
+		log.Println("could not get history: ", err)
 		return nil, err
 	}
-	vuelos := map[string]float64{}
 
-	type task struct {
-		sha  string
-		date string
-	}
-	ch := make(chan task, maxWorkers)
-	mutex := &sync.Mutex{}
-
-	wg := syncbits.Workgroup(func() {
-		for t := range ch {
-			hash := t.sha
-			date := t.date
-			content, err := githubStorage.ReadRef(path, hash)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "could not read ref: ", err)
-				return
-			}
-			var vuelo vueloArchivado
-			err = json.Unmarshal(content, &vuelo)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "could not decode archived flight: ", err)
-				return
-			}
-			price := calculatePrice(vuelo.Vuelo, vuelo.Services)
-			fmt.Println(date, price)
-			mutex.Lock()
-			vuelos[date] = price
-			mutex.Unlock()
+	for _, revision := range revisions {
+		var vuelo vueloArchivado
+		err = json.Unmarshal(revision.Content, &vuelo)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "could not decode archived flight: ", err)
+			continue
 		}
-	}, maxWorkers)
-
-	for date, hash := range hashes {
-		ch <- task{hash, date}
+		price, available := calculatePrice(vuelo.Vuelo, vuelo.Services)
+		if !available {
+			continue
+		}
+		date := revision.Date.UTC().Format(time.RFC3339)
+		fmt.Println(date, price)
+		vuelos[date] = price
 	}
-	close(ch)
-	wg.Wait()
 
 	return vuelos, nil
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/fabianMendez/wingo/pkg/i18n"
+	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/storage"
+	"github.com/fabianMendez/wingo/pkg/telegram"
+)
+
+// handleUpdate answers the messages sent to the bot. The confirmation emails
+// link to the bot with https://t.me/<bot>?start=<uid>_<token>, which sends
+// "/start <uid>_<token>": the chat is saved in the subscription, which gets
+// confirmed. The token can only be used once.
+func handleUpdate(ctx context.Context, client *telegram.Client, backend storage.Backend, update telegram.Update) error {
+	if update.Message == nil {
+		return nil
+	}
+
+	chatID := strconv.FormatInt(update.Message.Chat.ID, 10)
+	fields := strings.Fields(update.Message.Text)
+	if len(fields) == 0 || fields[0] != "/start" {
+		return nil
+	}
+
+	lang := i18n.Default
+	if update.Message.From != nil {
+		lang = i18n.Normalize(update.Message.From.LanguageCode)
+	}
+
+	if len(fields) == 1 {
+		return client.SendMessage(ctx, chatID, i18n.T(lang, "telegram.chat_id", chatID), "")
+	}
+
+	uid, token, ok := notifications.ParseTelegramStartParameter(fields[1])
+	if !ok {
+		return client.SendMessage(ctx, chatID, i18n.T(lang, "subscription.not_found"), "")
+	}
+
+	setting, err := notifications.GetSetting(backend, uid)
+	if err == nil {
+		err = setting.LinkTelegramChat(chatID, token)
+	}
+	if err != nil {
+		log.Println(err)
+		return client.SendMessage(ctx, chatID, i18n.T(lang, "subscription.not_found"), "")
+	}
+
+	err = notifications.UpdateSetting(backend, uid, setting)
+	if err != nil {
+		return err
+	}
+
+	text := i18n.T(setting.GetLanguage(), "telegram.confirmed", setting.Origin, setting.Destination, setting.DateLabel())
+	return client.SendMessage(ctx, chatID, telegram.EscapeMarkdownV2(text), telegram.ParseModeMarkdownV2)
+}
+
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	// without secret anyone could send updates as the bot
+	secret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	token := request.Headers["x-telegram-bot-api-secret-token"]
+	if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusUnauthorized}, nil
+	}
+
+	var update telegram.Update
+	err := json.Unmarshal([]byte(request.Body), &update)
+	if err != nil {
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: err.Error()}, nil
+	}
+
+	backend, err := storage.NewFromEnv(storage.KindGithub)
+	if err != nil {
+		return nil, err
+	}
+	defer storage.Close(backend)
+
+	err = handleUpdate(ctx, telegram.NewClientFromEnv(), backend, update)
+	if err != nil {
+		log.Println(err)
+	}
+
+	// Telegram retries the updates that are not answered with 200.
+	return &events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
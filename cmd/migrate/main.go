@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/fabianMendez/wingo/pkg/storage"
+)
+
+const outdir = "flights"
+
+func defaultIfEmpty(str, def string) string {
+	if str == "" {
+		return def
+	}
+	return str
+}
+
+// importFile copies every revision of fname, keeping the time it was observed.
+// The revisions already imported are skipped, so the import can be repeated.
+func importFile(src storage.Backend, dst *storage.SQLiteStorage, fname string) error {
+	revisions, err := src.History(fname, time.Time{})
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", fname, err)
+	}
+
+	for _, revision := range revisions {
+		present, err := dst.HasRevision(fname, revision.Date)
+		if err != nil {
+			return err
+		}
+		if present {
+			continue
+		}
+
+		err = dst.WriteAt(fname, revision.Content, revision.Date)
+		if err != nil {
+			return fmt.Errorf("could not import %s: %w", fname, err)
+		}
+	}
+
+	return nil
+}
+
+func importSubscriptions(src storage.Backend, dst *storage.SQLiteStorage, dir string) (int, error) {
+	names, err := src.List(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, name := range names {
+		if path.Ext(name) != ".json" {
+			continue
+		}
+
+		err = importFile(src, dst, path.Join(dir, name))
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// pathLister is implemented by the sources that know every file of their
+// history, including the deleted ones.
+type pathLister interface {
+	Paths(dir string) ([]string, error)
+}
+
+// flightPaths returns the files of the flights in src. The flights deleted
+// from a git source are included, as their history is still available.
+func flightPaths(src storage.Backend) ([]string, error) {
+	if lister, ok := src.(pathLister); ok {
+		paths, err := lister.Paths(outdir)
+		if err != nil {
+			return nil, err
+		}
+
+		fnames := []string{}
+		for _, p := range paths {
+			// <outdir>/<origin>/<destination>/<date>/<flight>.json
+			if len(strings.Split(p, "/")) == 5 && path.Ext(p) == ".json" {
+				fnames = append(fnames, p)
+			}
+		}
+		return fnames, nil
+	}
+
+	fnames := []string{}
+
+	origins, err := src.List(outdir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, origin := range origins {
+		destinations, err := src.List(path.Join(outdir, origin))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, destination := range destinations {
+			dates, err := src.List(path.Join(outdir, origin, destination))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, date := range dates {
+				datepath := path.Join(outdir, origin, destination, date)
+				names, err := src.List(datepath)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, name := range names {
+					if path.Ext(name) == ".json" {
+						fnames = append(fnames, path.Join(datepath, name))
+					}
+				}
+			}
+		}
+	}
+
+	return fnames, nil
+}
+
+func importFlights(src storage.Backend, dst *storage.SQLiteStorage) (int, error) {
+	fnames, err := flightPaths(src)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, fname := range fnames {
+		err = importFile(src, dst, fname)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// newSource returns the backend to import from: the history of the files is
+// read from git when dir is a working tree, otherwise only their current
+// revision is available.
+func newSource(dir string) storage.Backend {
+	err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run()
+	if err != nil {
+		log.Printf("%s is not a git working tree, only the current revisions are imported", dir)
+		return storage.NewLocal(dir)
+	}
+	return storage.NewGit(dir)
+}
+
+func main() {
+	srcDir := flag.String("src", defaultIfEmpty(os.Getenv("STORAGE_DIR"), "."), "directory containing the subscriptions and flights to import")
+	dbPath := flag.String("db", defaultIfEmpty(os.Getenv("SQLITE_PATH"), "wingo.db"), "sqlite database to import into")
+	flag.Parse()
+
+	src := newSource(*srcDir)
+	dst, err := storage.NewSQLite(*dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dst.Close()
+
+	subscriptionsDir := defaultIfEmpty(os.Getenv("GH_PATH"), "subscriptions")
+	dst.SubscriptionsDir = subscriptionsDir
+
+	count, err := importSubscriptions(src, dst, subscriptionsDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Subscriptions imported:", count)
+
+	count, err = importFlights(src, dst)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Flights imported:", count)
+}
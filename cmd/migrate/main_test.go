@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabianMendez/wingo/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportFlights(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	git("init", "-q")
+	src := newSource(dir)
+	require.NoError(t, src.Write("flights/BOG/CTG/2022-02-01/7013.json", []byte(`{"flightNumber":"7013"}`), ""))
+	require.NoError(t, src.Write("flights/BOG/CTG/2022-02-01/7014.json", []byte(`{"flightNumber":"7014"}`), ""))
+	git("add", "-A")
+	git("commit", "-q", "-m", "flights")
+	require.NoError(t, src.Delete("flights/BOG/CTG/2022-02-01/7014.json", ""))
+	git("commit", "-q", "-a", "-m", "flight 7014 not available")
+
+	dst, err := storage.NewSQLite(filepath.Join(t.TempDir(), "wingo.db"))
+	require.NoError(t, err)
+	defer dst.Close()
+
+	count, err := importFlights(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "the deleted flights are imported")
+
+	count, err = importFlights(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	revisions, err := dst.History("flights/BOG/CTG/2022-02-01/7014.json", time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, revisions, 1, "importing again does not duplicate the revisions")
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fabianMendez/wingo/pkg/i18n"
+	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/notify"
+)
+
+// digestKey identifies a single address, the channels it is notified
+// through and its language.
+type digestKey struct {
+	recipient notify.Recipient
+	channels  string
+	language  string
+}
+
+// digestRecord is a notification to record in the history once its digest
+// is sent.
+type digestRecord struct {
+	sub   notifications.Setting
+	key   string
+	event notify.Event
+}
+
+// digest collects the notifications of the subscriptions in digest mode, to
+// send a single message to each recipient at the end of the run.
+type digest struct {
+	mutex   *sync.Mutex
+	events  map[digestKey][]notify.Event
+	records map[digestKey][]digestRecord
+}
+
+func newDigest() *digest {
+	return &digest{
+		mutex:   new(sync.Mutex),
+		events:  map[digestKey][]notify.Event{},
+		records: map[digestKey][]digestRecord{},
+	}
+}
+
+var notificationDigest = newDigest()
+
+func (d *digest) add(sub notifications.Setting, event notify.Event, dedupeKey string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	channels := strings.Join(sub.Channels, ",")
+	for _, recipient := range sub.Recipient().Split() {
+		key := digestKey{recipient: recipient, channels: channels, language: sub.GetLanguage()}
+		d.events[key] = append(d.events[key], event)
+		d.records[key] = append(d.records[key], digestRecord{sub, dedupeKey, event})
+	}
+}
+
+func digestSubject(lang string, events []notify.Event) string {
+	if len(events) == 1 {
+		return i18n.T(lang, "digest.subject_one")
+	}
+	return i18n.T(lang, "digest.subject", len(events))
+}
+
+func (d *digest) sortedKeys() []digestKey {
+	keys := make([]digestKey, 0, len(d.events))
+	for key := range d.events {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].recipient.Email != keys[j].recipient.Email {
+			return keys[i].recipient.Email < keys[j].recipient.Email
+		}
+		if keys[i].recipient.PhoneNumber != keys[j].recipient.PhoneNumber {
+			return keys[i].recipient.PhoneNumber < keys[j].recipient.PhoneNumber
+		}
+		if keys[i].channels != keys[j].channels {
+			return keys[i].channels < keys[j].channels
+		}
+		return keys[i].language < keys[j].language
+	})
+	return keys
+}
+
+// record records the notifications of a sent digest in the history.
+func (d *digest) record(key digestKey) error {
+	for _, record := range d.records[key] {
+		err := recordNotification(record.sub, record.key, record.event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send sends the collected notifications, one message per address and
+// channel. A failure for a recipient does not stop the others.
+func (d *digest) send(ctx context.Context, notifier notify.Dispatcher) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var firstErr error
+	for _, key := range d.sortedKeys() {
+		events := d.events[key]
+		event := notify.Event{
+			Type:     notify.EventDigest,
+			Language: key.language,
+			Subject:  digestSubject(key.language, events),
+			Events:   events,
+		}
+
+		var channels []string
+		if key.channels != "" {
+			channels = strings.Split(key.channels, ",")
+		}
+
+		fmt.Println("["+key.recipient.Email+key.recipient.PhoneNumber+"]:", event.Subject)
+		err := notifier.Send(ctx, channels, key.recipient, event)
+		if err == nil {
+			err = d.record(key)
+		}
+		if err != nil {
+			log.Print(err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("could not send digest: %w", err)
+			}
+		}
+	}
+
+	d.events = map[digestKey][]notify.Event{}
+	d.records = map[digestKey][]digestRecord{}
+	return firstErr
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+
+	"github.com/fabianMendez/wingo/pkg/currency"
+	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/storage"
+)
+
+const flexibleDir = "flexible"
+
+func flexiblePath(uid string) string {
+	return path.Join(flexibleDir, uid+".json")
+}
+
+func loadCheapestFlight(backend storage.Backend, uid string) (vueloFlexible, bool, error) {
+	var vuelo vueloFlexible
+
+	content, err := backend.Read(flexiblePath(uid))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return vuelo, false, nil
+		}
+		return vuelo, false, err
+	}
+
+	err = json.Unmarshal(content, &vuelo)
+	if err != nil {
+		return vuelo, false, fmt.Errorf("could not decode cheapest flight: %w", err)
+	}
+
+	return vuelo, true, nil
+}
+
+func saveCheapestFlight(backend storage.Backend, uid string, vuelo vueloFlexible) error {
+	b, err := json.MarshalIndent(vuelo, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return backend.Write(flexiblePath(uid), b, fmt.Sprintf("update cheapest flight %s", uid))
+}
+
+// cheapestFlight returns the cheapest flight of actualFlights matching the
+// routes, dates, currency, passengers and bundle of sub.
+func cheapestFlight(sub notifications.Setting, actualFlights flightsMap) (vueloFlexible, bool) {
+	var cheapest vueloFlexible
+	found := false
+
+	for _, pair := range sub.RoutePairs() {
+		for date, flights := range actualFlights[pair.Origin][pair.Destination] {
+			if !sub.MatchesDate(date) {
+				continue
+			}
+
+			for _, flight := range flights {
+				if currency.Normalize(flight.Currency) != sub.GetCurrency() || !sub.MatchesFlight(flight.FlightNumber) {
+					continue
+				}
+
+				price, available := calculatePrice(flight.Vuelo, flight.Services, sub.GetPassengers(), sub.GetBundle())
+				if !available {
+					continue
+				}
+
+				if !found || price < cheapest.Price || (price == cheapest.Price && date < cheapest.Date) {
+					cheapest = vueloFlexible{
+						Origin:      pair.Origin,
+						Destination: pair.Destination,
+						Date:        date,
+						Vuelo:       flight.Vuelo,
+						Price:       price,
+					}
+					found = true
+				}
+			}
+		}
+	}
+
+	return cheapest, found
+}
+
+// processFlexibleSubscriptions notifies the subscriptions covering several
+// dates or airports about the cheapest flight among them. A failure for a
+// subscription does not stop the others; the first one is returned.
+func processFlexibleSubscriptions(backend storage.Backend, subs []notifications.Setting, actualFlights flightsMap) error {
+	var firstErr error
+	for _, sub := range subs {
+		if !sub.NotifiesCheapest() {
+			continue
+		}
+
+		err := processFlexibleSubscription(backend, sub, actualFlights)
+		if err != nil {
+			log.Printf("could not process subscription %s: %v", sub.UID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+func processFlexibleSubscription(backend storage.Backend, sub notifications.Setting, actualFlights flightsMap) error {
+	recipients := []notifications.Setting{sub}
+
+	previous, previousFound, err := loadCheapestFlight(backend, sub.UID)
+	if err != nil {
+		return err
+	}
+
+	cheapest, found := cheapestFlight(sub, actualFlights)
+	if !found {
+		if previousFound {
+			_ = backend.Delete(flexiblePath(sub.UID), "remove cheapest flight")
+			return sendNotAvailableNotification(recipients, previous.Origin, previous.Destination, previous.Date, previous.FlightNumber, previous.Price)
+		}
+		return nil
+	}
+
+	err = saveCheapestFlight(backend, sub.UID, cheapest)
+	if err != nil {
+		return err
+	}
+
+	cur := sub.GetCurrency()
+	if !previousFound {
+		if !sub.Rule().ShouldNotifyNew(cheapest.Price) {
+			return nil
+		}
+		return sendNewFlightNotification(recipients, cheapest.Origin, cheapest.Destination, cheapest.Date, cheapest.FlightNumber, cur, cheapest.Price)
+	}
+
+	return sendPriceChangedNotifications(recipients, cheapest.Origin, cheapest.Destination, cheapest.Date, cheapest.FlightNumber, cur, previous.Price, cheapest.Price)
+}
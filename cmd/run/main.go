@@ -6,31 +6,39 @@ import (
 	"log"
 	"net/url"
 	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/fabianMendez/bits/syncbits"
 	"github.com/fabianMendez/wingo"
+	"github.com/fabianMendez/wingo/pkg/currency"
 	"github.com/fabianMendez/wingo/pkg/date"
-	"github.com/fabianMendez/wingo/pkg/email"
+	"github.com/fabianMendez/wingo/pkg/i18n"
 	"github.com/fabianMendez/wingo/pkg/notifications"
-	"github.com/fabianMendez/wingo/pkg/whatsapp"
+	"github.com/fabianMendez/wingo/pkg/notify"
+	"github.com/fabianMendez/wingo/pkg/storage"
 )
 
 var (
 	logger = log.Default()
 )
 
+// notifier sends the notifications, through the outbox created in main.
+var notifier notify.Dispatcher = notify.NewRegistry()
+
+// notificationHistory prevents repeating notifications within the cooldown
+// of the subscriptions.
+var notificationHistory *notifications.History
+
 const (
 	outdir     = "flights"
 	maxWorkers = 10
 )
 
-func formatMoney(n float64) string { return "$" + humanize.FormatFloat("#,###.##", n) }
+func formatMoney(n float64, cur string) string { return currency.Format(n, cur) }
 
 func filtrarVuelos(vuelos []wingo.VueloIda) map[string][]wingo.Vuelo {
 	filtrados := map[string][]wingo.Vuelo{}
@@ -38,8 +46,7 @@ func filtrarVuelos(vuelos []wingo.VueloIda) map[string][]wingo.Vuelo {
 	for _, flight := range vuelos {
 		if len(flight.InfoVuelo.Vuelos) > 0 {
 			for _, vuelo := range flight.InfoVuelo.Vuelos {
-				price := wingo.GetBundlePrice(wingo.OriginalPlanName, vuelo, 0)
-				if price != 0 {
+				if _, available := wingo.GetBundlePrice(wingo.BundleBasic, vuelo, nil); available {
 					filtrados[flight.Fecha] = append(filtrados[flight.Fecha], vuelo)
 					// log.Printf("buscando tarifas servicios del vuelo %s - %s\n", vuelo.FlightNumber, vuelo.DepartureDate)
 					// []wingo.FlightService{ {Departure: flight.Fecha, From: origin, To: destination, FlightID: vuelo.LogicalFlightID}, }, flightsInformation.Token
@@ -56,7 +63,13 @@ var mx sync.Mutex
 var cacheMx map[string]*sync.Mutex
 var serviceCache map[string][]wingo.Service
 
-func printInformation(client *wingo.Client, fecha string, vuelo wingo.Vuelo, origin, destination, token string) ([]wingo.Service, error) {
+// serviceCacheKey identifies the services quoted for a flight: they depend
+// on the flight itself, not only on its route.
+func serviceCacheKey(origin, destination, fecha string, logicalFlightID int64, cur string) string {
+	return fmt.Sprintf("%s-%s-%s-%d-%s", origin, destination, fecha, logicalFlightID, currency.Normalize(cur))
+}
+
+func printInformation(ctx context.Context, client *wingo.Client, fecha string, vuelo wingo.Vuelo, origin, destination, token, cur string) ([]wingo.Service, error) {
 	log.Printf("buscando tarifas servicios del vuelo %s-%s (%s): %s - %s\n", origin, destination, vuelo.DepartureDate, vuelo.FlightNumber, vuelo.DepartureDate)
 	now := time.Now()
 
@@ -67,7 +80,7 @@ func printInformation(client *wingo.Client, fecha string, vuelo wingo.Vuelo, ori
 	if serviceCache == nil {
 		serviceCache = make(map[string][]wingo.Service)
 	}
-	key := origin + "-" + destination
+	key := serviceCacheKey(origin, destination, fecha, vuelo.LogicalFlightID, cur)
 	mx2 := cacheMx[key]
 	if mx2 == nil {
 		mx2 = new(sync.Mutex)
@@ -85,7 +98,7 @@ func printInformation(client *wingo.Client, fecha string, vuelo wingo.Vuelo, ori
 	}
 	mx.Unlock()
 
-	serviceQuotes, err := client.RetrieveServiceQuotes([]wingo.FlightService{
+	serviceQuotes, err := client.RetrieveServiceQuotes(ctx, []wingo.FlightService{
 		{
 			Departure:              fecha,
 			AnticipationDateFlight: now.Format(time.RFC3339),
@@ -93,18 +106,22 @@ func printInformation(client *wingo.Client, fecha string, vuelo wingo.Vuelo, ori
 			To:                     destination,
 			FlightID:               vuelo.LogicalFlightID,
 		},
-	}, token)
+	}, token, cur)
 	if err != nil {
 		mx2.Unlock()
 		return nil, err
 	}
+	if len(serviceQuotes) == 0 {
+		mx2.Unlock()
+		return nil, fmt.Errorf("no service quotes for flight %s %s-%s (%s)", vuelo.FlightNumber, origin, destination, fecha)
+	}
 	log.Printf("tarifas encontradas del vuelo %s-%s (%s): %s - %s\n", origin, destination, vuelo.DepartureDate, vuelo.FlightNumber, vuelo.DepartureDate)
 
 	// log.Println("calculando precio")
 	// adminFares := wingo.GetAdminFares(serviceQuotes[0])
 	// precio := wingo.GetBundlePrice(wingo.OriginalPlanName, vuelo, adminFares)
 
-	// log.Printf("precio del vuelo %s-%s (%s - %s): %s\n", origin, destination, vuelo.FlightNumber, vuelo.DepartureDate, formatMoney(precio))
+	// log.Printf("precio del vuelo %s-%s (%s - %s): %s\n", origin, destination, vuelo.FlightNumber, vuelo.DepartureDate, formatMoney(precio, cur))
 
 	services := serviceQuotes[0].Services
 	mx.Lock()
@@ -116,69 +133,199 @@ func printInformation(client *wingo.Client, fecha string, vuelo wingo.Vuelo, ori
 	return services, nil
 }
 
-func sendNotificationEmail(notificationSettings []notifications.Setting, origin, destination, date, flightNumber string, message, body string) error {
+// sendNotificationEvent sends event to the subscriptions for its route, date
+// and flight, adding the links of each subscription and its message, built
+// by format in the language of the subscription.
+func sendNotificationEvent(notificationSettings []notifications.Setting, event notify.Event, format func(lang string) (string, []string)) error {
+	origin, destination, date, flightNumber := event.Origin, event.Destination, event.Date, event.FlightNumber
 	subs := notifications.GroupByRoute(notificationSettings)[origin][destination]
-	heading := fmt.Sprintf("✈️ %s-%s/%s", origin, destination, date)
+	event.Subject = fmt.Sprintf("✈️ %s-%s/%s", origin, destination, date)
 	baseURL := os.Getenv("BASE_URL")
 
-	link := fmt.Sprintf("https://booking.wingo.com/es/search/%s/%s/%s/1/0/0/1/COP/0/0", origin, destination, date)
-	linkHistory := fmt.Sprintf("%s/history?origin=%s&destination=%s&date=%s&flightNumber=%s", baseURL,
-		url.QueryEscape(origin), url.QueryEscape(destination), url.QueryEscape(date), url.QueryEscape(flightNumber))
-
+	var firstErr error
 	for _, sub := range subs {
-		if sub.Date != date {
+		if !sub.MatchesDate(date) || !sub.MatchesFlight(flightNumber) {
 			continue
 		}
-		cancelSubscriptionLink := fmt.Sprintf("%s/.netlify/functions/cancel_subscription?uid=%s", baseURL, sub.UID)
-
-		fmt.Println("["+sub.Email+"]:", heading, message)
-		data := map[string]interface{}{
-			"Message":                message,
-			"Link":                   link,
-			"LinkHistory":            linkHistory,
-			"CancelSubscriptionLink": cancelSubscriptionLink,
-		}
-		err := email.SendMessageWithText(context.Background(), heading, message, email.TplPriceChange, data, strings.Split(sub.Email, ",")...)
+		passengers := sub.GetPassengers()
+		subEvent := event
+		subEvent.SubscriptionUID = sub.UID
+		subEvent.Language = sub.GetLanguage()
+		subEvent.Message, subEvent.Details = format(subEvent.Language)
+		subEvent.Link = fmt.Sprintf("https://booking.wingo.com/%s/search/%s/%s/%s/%d/%d/%d/1/%s/0/0", subEvent.Language, origin, destination, date,
+			passengers.Adults, passengers.Children, passengers.Infants, sub.GetCurrency())
+		subEvent.LinkHistory = fmt.Sprintf("%s/history?origin=%s&destination=%s&date=%s&flightNumber=%s", baseURL,
+			url.QueryEscape(origin), url.QueryEscape(destination), url.QueryEscape(date),
+			url.QueryEscape(archiveName(flightNumber, sub.GetCurrency())))
+		subEvent.CancelSubscriptionLink = fmt.Sprintf("%s/.netlify/functions/cancel_subscription?uid=%s", baseURL, sub.UID)
+
+		key := subEvent.DedupeKey()
+		allowed, err := notificationHistory.Allow(sub, key)
 		if err != nil {
-			return err
+			log.Print(err)
+		}
+		if !allowed {
+			continue
 		}
 
-		if len(sub.PhoneNumber) != 0 {
-			err = whatsapp.SendMessage(sub.PhoneNumber, heading, message)
-			if err != nil {
-				log.Print(err)
+		if sub.Digest {
+			notificationDigest.add(sub, subEvent, key)
+			continue
+		}
+
+		err = sendNotification(sub, subEvent)
+		if err == nil {
+			err = recordNotification(sub, key, subEvent)
+		}
+		if err != nil {
+			log.Print(err)
+			if firstErr == nil {
+				firstErr = err
 			}
 		}
 	}
 
-	return nil
+	return firstErr
 }
 
-func sendNewFlightNotification(notificationSettings []notifications.Setting, origin, destination, date, flightNumber string, price float64) error {
-	subject := fmt.Sprintf("Precio actual: %s.", formatMoney(price))
+// recordNotification records a notification sent to the subscription, and
+// the price it was notified.
+func recordNotification(sub notifications.Setting, key string, event notify.Event) error {
+	err := notificationHistory.Record(sub, key)
+	if err != nil {
+		return err
+	}
+	return notificationHistory.RecordPrice(sub, event)
+}
+
+// keepFirstError stores err in first unless there was already an error, so
+// that a failure for a subscriber does not stop the others.
+func keepFirstError(first *error, err error) {
+	if *first == nil {
+		*first = err
+	}
+}
 
-	return sendNotificationEmail(notificationSettings, origin, destination, date, flightNumber, subject, "")
+func sendNotification(sub notifications.Setting, event notify.Event) error {
+	fmt.Println("["+sub.Email+"]:", event.Subject, event.Message)
+	return notifier.Send(context.Background(), sub.Channels, sub.Recipient(), event)
 }
 
-func sendPriceChangedNotification(notificationSettings []notifications.Setting, origin, destination, date, flightNumber string, oldPrice, newPrice float64) error {
-	emoji := "↗️"
-	accion := "SUBIÓ"
+func sendNewFlightNotification(notificationSettings []notifications.Setting, origin, destination, date, flightNumber, cur string, price float64) error {
+	return sendNotificationEvent(notificationSettings, notify.Event{
+		Type:         notify.EventNewFlight,
+		Origin:       origin,
+		Destination:  destination,
+		Date:         date,
+		FlightNumber: flightNumber,
+		Currency:     cur,
+		NewPrice:     price,
+	}, func(lang string) (string, []string) {
+		return i18n.T(lang, "price.current", formatMoney(price, cur)), nil
+	})
+}
+
+func sendPriceChangedNotification(notificationSettings []notifications.Setting, origin, destination, date, flightNumber, cur string, oldPrice, newPrice float64) error {
+	key := "price.up"
 	if oldPrice > newPrice {
-		emoji = "↘️"
-		accion = "BAJÓ"
+		key = "price.down"
 	}
 
-	subject := fmt.Sprintf("%s El precio %s a %s (desde %s).", emoji, accion, formatMoney(newPrice), formatMoney(oldPrice))
+	return sendNotificationEvent(notificationSettings, notify.Event{
+		Type:         notify.EventPriceChanged,
+		Origin:       origin,
+		Destination:  destination,
+		Date:         date,
+		FlightNumber: flightNumber,
+		Currency:     cur,
+		OldPrice:     oldPrice,
+		NewPrice:     newPrice,
+	}, func(lang string) (string, []string) {
+		return i18n.T(lang, key, formatMoney(newPrice, cur), formatMoney(oldPrice, cur)), nil
+	})
+}
 
-	return sendNotificationEmail(notificationSettings, origin, destination, date, flightNumber, subject, "")
+// sendPriceChangedNotifications notifies each subscription about the change
+// to newPrice from the last price notified to it, or from savedPrice when
+// none was, so that small changes adding up are not missed by min_change.
+func sendPriceChangedNotifications(notificationSettings []notifications.Setting, origin, destination, date, flightNumber, cur string, savedPrice, newPrice float64) error {
+	var firstErr error
+	for _, sub := range notificationSettings {
+		oldPrice, found, err := notificationHistory.LastPrice(sub, origin, destination, date, flightNumber, cur)
+		if err != nil {
+			log.Print(err)
+		}
+		if !found {
+			oldPrice = savedPrice
+		}
+		if !sub.Rule().ShouldNotifyChange(oldPrice, newPrice) {
+			continue
+		}
+
+		err = sendPriceChangedNotification([]notifications.Setting{sub}, origin, destination, date, flightNumber, cur, oldPrice, newPrice)
+		keepFirstError(&firstErr, err)
+	}
+	return firstErr
 }
 
 func sendNotAvailableNotification(notificationSettings []notifications.Setting, origin, destination, date, flightNumber string, lastPrice float64) error {
-	subject := "El vuelo ya NO está disponible."
-	return sendNotificationEmail(notificationSettings, origin, destination, date, flightNumber, subject, "")
+	return sendNotificationEvent(notificationSettings, notify.Event{
+		Type:         notify.EventNotAvailable,
+		Origin:       origin,
+		Destination:  destination,
+		Date:         date,
+		FlightNumber: flightNumber,
+		OldPrice:     lastPrice,
+	}, func(lang string) (string, []string) {
+		return i18n.T(lang, "flight.not_available"), nil
+	})
+}
+
+func sendLowSeatsNotification(notificationSettings []notifications.Setting, origin, destination, date, flightNumber, cur string, seats int64, price float64) error {
+	return sendNotificationEvent(notificationSettings, notify.Event{
+		Type:         notify.EventLowSeats,
+		Origin:       origin,
+		Destination:  destination,
+		Date:         date,
+		FlightNumber: flightNumber,
+		Currency:     cur,
+		NewPrice:     price,
+		Seats:        seats,
+	}, func(lang string) (string, []string) {
+		if seats == 1 {
+			return i18n.T(lang, "seats.low_one", formatMoney(price, cur)), nil
+		}
+		return i18n.T(lang, "seats.low", seats, formatMoney(price, cur)), nil
+	})
 }
 
-func convertToTasks(flightsInformation wingo.FlightsInformation, origin, destination string) []getPriceTask {
+func sendBaggageFeesChangedNotification(notificationSettings []notifications.Setting, origin, destination, date, flightNumber, cur string, changes []wingo.AncillaryChange) error {
+	return sendNotificationEvent(notificationSettings, notify.Event{
+		Type:         notify.EventBaggageFeesChanged,
+		Origin:       origin,
+		Destination:  destination,
+		Date:         date,
+		FlightNumber: flightNumber,
+		Currency:     cur,
+	}, func(lang string) (string, []string) {
+		details := []string{}
+		for _, change := range changes {
+			ancillary := change.Ancillary()
+			switch {
+			case change.Old == nil:
+				details = append(details, i18n.T(lang, "baggage.new", ancillary.Description, formatMoney(change.New.Price, cur)))
+			case change.New == nil:
+				details = append(details, i18n.T(lang, "baggage.not_available", ancillary.Description))
+			default:
+				details = append(details, i18n.T(lang, "baggage.price", ancillary.Description,
+					formatMoney(change.New.Price, cur), formatMoney(change.Old.Price, cur)))
+			}
+		}
+		return i18n.T(lang, "baggage.changed"), details
+	})
+}
+
+func convertToTasks(flightsInformation wingo.FlightsInformation, origin, destination, cur string) []getPriceTask {
 	var tasks []getPriceTask
 
 	fechaVuelos := filtrarVuelos(flightsInformation.VueloIda)
@@ -189,6 +336,7 @@ func convertToTasks(flightsInformation wingo.FlightsInformation, origin, destina
 				token:       flightsInformation.Token,
 				origin:      origin,
 				destination: destination,
+				currency:    cur,
 				vuelo:       vuelo,
 			})
 		}
@@ -197,22 +345,49 @@ func convertToTasks(flightsInformation wingo.FlightsInformation, origin, destina
 	return tasks
 }
 
-func calculatePrice(vuelo wingo.Vuelo, services []wingo.Service) float64 {
-	adminFares := wingo.GetAdminFares(wingo.ServiceQuote{
-		Services: services,
-	})
+func calculatePrice(vuelo wingo.Vuelo, services []wingo.Service, passengers wingo.Passengers, bundle string) (float64, bool) {
+	return wingo.GetPassengersBundlePrice(bundle, vuelo, services, passengers)
+}
 
-	return wingo.GetBundlePrice(wingo.OriginalPlanName, vuelo, adminFares)
+func subscriptionsFor(subs []notifications.Setting, origin, destination, date, cur string) []notifications.Setting {
+	filtered := []notifications.Setting{}
+	for _, sub := range notifications.GroupByRoute(subs)[origin][destination] {
+		if !sub.NotifiesCheapest() && sub.Date == date && sub.GetCurrency() == currency.Normalize(cur) {
+			filtered = append(filtered, sub)
+		}
+	}
+	return filtered
 }
 
-func findFlight(savedFlights flightsMap, origin, destination, date, flightNumber string) (vueloArchivado, bool) {
+// filterSubscriptions returns the subscriptions for which fn returns true.
+func filterSubscriptions(subs []notifications.Setting, fn func(notifications.Setting) bool) []notifications.Setting {
+	filtered := []notifications.Setting{}
+	for _, sub := range subs {
+		if fn(sub) {
+			filtered = append(filtered, sub)
+		}
+	}
+	return filtered
+}
+
+// maxPassengers returns a passenger mix including every passenger type
+// requested by subs, so that a single search returns all the needed fares.
+func maxPassengers(subs []notifications.Setting) wingo.Passengers {
+	passengers := wingo.SingleAdult
+	for _, sub := range subs {
+		passengers = passengers.Max(sub.GetPassengers())
+	}
+	return passengers
+}
+
+func findFlight(savedFlights flightsMap, origin, destination, date, flightNumber, cur string) (vueloArchivado, bool) {
 	for savedDate, flights := range savedFlights[origin][destination] {
 		if savedDate != date {
 			continue
 		}
 
 		for _, flight := range flights {
-			if flight.FlightNumber == flightNumber {
+			if flight.FlightNumber == flightNumber && currency.Normalize(flight.Currency) == currency.Normalize(cur) {
 				return flight, true
 			}
 		}
@@ -222,111 +397,175 @@ func findFlight(savedFlights flightsMap, origin, destination, date, flightNumber
 }
 
 func processFlight(notificationSettings []notifications.Setting, savedFlights flightsMap, date, origin, destination string, flight vueloArchivado) error {
-	previous, previousFound := findFlight(savedFlights, origin, destination, date, flight.FlightNumber)
+	var firstErr error
+	previous, previousFound := findFlight(savedFlights, origin, destination, date, flight.FlightNumber, flight.Currency)
+
+	for _, sub := range subscriptionsFor(notificationSettings, origin, destination, date, flight.Currency) {
+		passengers := sub.GetPassengers()
+		recipients := []notifications.Setting{sub}
+
+		if sub.BaggageAlerts && previousFound {
+			changes := wingo.DiffAncillaries(
+				previous.GetAncillaries().Filter(wingo.AncillaryKind.IsBaggage),
+				flight.GetAncillaries().Filter(wingo.AncillaryKind.IsBaggage))
+			if len(changes) != 0 {
+				err := sendBaggageFeesChangedNotification(recipients, origin, destination, date, flight.FlightNumber, flight.Currency, changes)
+				keepFirstError(&firstErr, err)
+			}
+		}
 
-	price := calculatePrice(flight.Vuelo, flight.Services)
-	// 1. Antes NO disponible y ahora disponible?
-	if !previousFound {
-		err := sendNewFlightNotification(notificationSettings, origin, destination, date, flight.FlightNumber, price)
-		if err != nil {
-			return err
+		price, available := calculatePrice(flight.Vuelo, flight.Services, passengers, sub.GetBundle())
+		if !available {
+			// the fares for this passenger mix or the bundle are not available
+			continue
 		}
-	} else {
-		savedPrice := calculatePrice(previous.Vuelo, previous.Services)
-		// 2. Antes disponible y ahora diferente precio?
-		if price != savedPrice {
-			err := sendPriceChangedNotification(notificationSettings, origin, destination, date, flight.FlightNumber, savedPrice, price)
-			if err != nil {
-				return err
+
+		if seats := wingo.SeatsAvailable(flight.Vuelo, passengers); seats > 0 && seats < sub.SeatsThreshold {
+			savedSeats := int64(0)
+			if previousFound {
+				savedSeats = wingo.SeatsAvailable(previous.Vuelo, passengers)
+			}
+			if savedSeats == 0 || seats < savedSeats {
+				err := sendLowSeatsNotification(recipients, origin, destination, date, flight.FlightNumber, flight.Currency, seats, price)
+				keepFirstError(&firstErr, err)
 			}
 		}
+
+		var savedPrice float64
+		savedAvailable := false
+		if previousFound {
+			savedPrice, savedAvailable = calculatePrice(previous.Vuelo, previous.Services, passengers, sub.GetBundle())
+		}
+
+		// 1. Antes NO disponible y ahora disponible?
+		if !savedAvailable {
+			if !sub.Rule().ShouldNotifyNew(price) {
+				continue
+			}
+			err := sendNewFlightNotification(recipients, origin, destination, date, flight.FlightNumber, flight.Currency, price)
+			keepFirstError(&firstErr, err)
+		} else {
+			// 2. Antes disponible y ahora diferente precio?
+			err := sendPriceChangedNotifications(recipients, origin, destination, date, flight.FlightNumber, flight.Currency, savedPrice, price)
+			keepFirstError(&firstErr, err)
+		}
 	}
 
-	return nil
+	return firstErr
 }
 
-func processUnavailableFlights(notificationSettings []notifications.Setting, savedFlights flightsMap, actualFlights flightsMap) error {
+func processUnavailableFlights(backend storage.Backend, notificationSettings []notifications.Setting, savedFlights flightsMap, actualFlights flightsMap) error {
+	var firstErr error
 	// 3. Antes disponible y ahora NO disponible?
 	for origin, originMap := range savedFlights {
 		for destination, destinationMap := range originMap {
 			for date, savedFlights := range destinationMap {
 				for _, savedFlight := range savedFlights {
-					_, actualFound := findFlight(actualFlights, origin, destination, date, savedFlight.FlightNumber)
+					_, actualFound := findFlight(actualFlights, origin, destination, date, savedFlight.FlightNumber, savedFlight.Currency)
 					if !actualFound {
-						flightpath := filepath.Join(outdir, origin, destination, date, savedFlight.FlightNumber+".json")
-						_ = os.Remove(flightpath)
+						_ = deleteFlight(backend, origin, destination, date, savedFlight.FlightNumber, savedFlight.Currency)
 
-						savedPrice := calculatePrice(savedFlight.Vuelo, savedFlight.Services)
-						err := sendNotAvailableNotification(notificationSettings, origin, destination, date, savedFlight.FlightNumber, savedPrice)
-						if err != nil {
-							return err
+						savedPrice, savedAvailable := calculatePrice(savedFlight.Vuelo, savedFlight.Services, wingo.SingleAdult, wingo.BundleBasic)
+						if !savedAvailable {
+							continue
 						}
+
+						recipients := subscriptionsFor(notificationSettings, origin, destination, date, savedFlight.Currency)
+						err := sendNotAvailableNotification(recipients, origin, destination, date, savedFlight.FlightNumber, savedPrice)
+						keepFirstError(&firstErr, err)
 					}
 				}
 			}
 		}
 	}
 
-	return nil
+	return firstErr
 }
 
-func processUnavailableFlightsForSubs(subs []notifications.Setting, savedFlights flightsMap, actualFlights flightsMap) error {
+func processUnavailableFlightsForSubs(backend storage.Backend, subs []notifications.Setting, savedFlights flightsMap, actualFlights flightsMap) error {
+	var firstErr error
 	subsByRoute := notifications.GroupByRoute(subs)
 
 	// 3. Antes disponible y ahora NO disponible?
 	for origin, originSubs := range subsByRoute {
 		for destination, destinationSubs := range originSubs {
 			for _, sub := range destinationSubs {
-				date := sub.Date
-				savedFlights := savedFlights[origin][destination][date]
-				for _, savedFlight := range savedFlights {
-					_, actualFound := findFlight(actualFlights, origin, destination, date, savedFlight.FlightNumber)
-					if !actualFound {
-						flightpath := filepath.Join(outdir, origin, destination, date, savedFlight.FlightNumber+".json")
-						_ = os.Remove(flightpath)
+				for date, savedFlights := range savedFlights[origin][destination] {
+					if !sub.MatchesDate(date) {
+						continue
+					}
+
+					for _, savedFlight := range savedFlights {
+						if currency.Normalize(savedFlight.Currency) != sub.GetCurrency() {
+							continue
+						}
 
-						savedPrice := calculatePrice(savedFlight.Vuelo, savedFlight.Services)
-						err := sendNotAvailableNotification(subs, origin, destination, date, savedFlight.FlightNumber, savedPrice)
-						if err != nil {
-							return err
+						_, actualFound := findFlight(actualFlights, origin, destination, date, savedFlight.FlightNumber, savedFlight.Currency)
+						if actualFound {
+							continue
 						}
+
+						_ = deleteFlight(backend, origin, destination, date, savedFlight.FlightNumber, savedFlight.Currency)
+						if sub.NotifiesCheapest() {
+							// notified by processFlexibleSubscriptions
+							continue
+						}
+
+						savedPrice, savedAvailable := calculatePrice(savedFlight.Vuelo, savedFlight.Services, sub.GetPassengers(), sub.GetBundle())
+						if !savedAvailable {
+							// the subscription was never notified about this flight
+							continue
+						}
+
+						err := sendNotAvailableNotification([]notifications.Setting{sub}, origin, destination, date, savedFlight.FlightNumber, savedPrice)
+						keepFirstError(&firstErr, err)
 					}
 				}
 			}
 		}
 	}
 
-	return nil
+	return firstErr
 }
 
 func processSchedule(notificationSettings []notifications.Setting,
 	savedFlights flightsMap, date, origin, destination string, flight vueloArchivado) error {
-	previous, previousFound := findFlight(savedFlights, origin, destination, date, flight.FlightNumber)
+	previous, previousFound := findFlight(savedFlights, origin, destination, date, flight.FlightNumber, currency.Default)
+
+	price, available := calculatePrice(flight.Vuelo, flight.Services, wingo.SingleAdult, wingo.BundleBasic)
+	if !available {
+		return nil
+	}
+
+	var savedPrice float64
+	savedAvailable := false
+	if previousFound {
+		savedPrice, savedAvailable = calculatePrice(previous.Vuelo, previous.Services, wingo.SingleAdult, wingo.BundleBasic)
+	}
 
-	price := calculatePrice(flight.Vuelo, flight.Services)
 	// 1. Antes NO disponible y ahora disponible?
-	if !previousFound {
-		err := sendNewFlightNotification(notificationSettings, origin, destination, date, flight.FlightNumber, price)
+	if !savedAvailable {
+		recipients := filterSubscriptions(notificationSettings, func(sub notifications.Setting) bool {
+			return sub.Rule().ShouldNotifyNew(price)
+		})
+		err := sendNewFlightNotification(recipients, origin, destination, date, flight.FlightNumber, currency.Default, price)
 		if err != nil {
 			return err
 		}
 	} else {
-		savedPrice := calculatePrice(previous.Vuelo, previous.Services)
 		// 2. Antes disponible y ahora diferente precio?
-		if price != savedPrice {
-			err := sendPriceChangedNotification(notificationSettings, origin, destination, date, flight.FlightNumber, savedPrice, price)
-			if err != nil {
-				return err
-			}
+		err := sendPriceChangedNotifications(notificationSettings, origin, destination, date, flight.FlightNumber, currency.Default, savedPrice, price)
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func retrieveServices(client *wingo.Client, getPriceTaskChan chan getPriceTask, archiveTasksChan chan<- archiveTask) {
+func retrieveServices(ctx context.Context, client *wingo.Client, getPriceTaskChan chan getPriceTask, archiveTasksChan chan<- archiveTask) {
 	for task := range getPriceTaskChan {
-		services, err := printInformation(client, task.fecha, task.vuelo, task.origin, task.destination, task.token)
+		services, err := printInformation(ctx, client, task.fecha, task.vuelo, task.origin, task.destination, task.token, task.currency)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			continue
@@ -337,22 +576,31 @@ func retrieveServices(client *wingo.Client, getPriceTaskChan chan getPriceTask,
 			vuelo:       task.vuelo,
 			origin:      task.origin,
 			destination: task.destination,
+			currency:    task.currency,
 			services:    services,
 		}
 	}
 }
 
-func getInformationFlightsMonthly(client *wingo.Client, origin, destination string, startDate, endDate time.Time) []getPriceTask {
+func getInformationFlightsMonthly(ctx context.Context, client *wingo.Client, origin, destination, cur string, startDate, endDate time.Time, passengers wingo.Passengers) []getPriceTask {
 	// fmt.Println(startDate, endDate, endDate.Sub(startDate).Hours())
 	daysAfter := int(endDate.Sub(startDate).Hours() / 24)
 	// fmt.Printf("Start date %s-%s: %s", origin, destination, date.Format(startDate))
 	// fmt.Printf(" | Days %s-%s: %d\n", origin, destination, daysAfter)
-	flightsInformation, err := client.GetInformationFlightsMonthly(origin, destination, date.Format(startDate), daysAfter)
+	flightsInformation, err := client.SearchFlights(ctx, wingo.FlightSearch{
+		Origin:      origin,
+		Destination: destination,
+		StartDate:   date.Format(startDate),
+		DaysAfter:   daysAfter,
+		Passengers:  passengers,
+		Currency:    cur,
+	})
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil
 	}
 
-	tasks := convertToTasks(flightsInformation, origin, destination)
+	tasks := convertToTasks(flightsInformation, origin, destination, cur)
 	fmt.Printf("Got flightsInformation %s/%s (%s - %s): %d\n", origin, destination, startDate, endDate, len(tasks))
 	return tasks
 
@@ -375,15 +623,6 @@ func checkDate(startDate, endDate time.Time, dateStr string) error {
 	return nil
 }
 
-func cleanFlightNumber(flightNumber string) string {
-	i := strings.Index(flightNumber, "-")
-	if i != -1 {
-		return flightNumber[i+1:]
-	}
-
-	return flightNumber
-}
-
 func addFlightToMap(fmap flightsMap, origin, destination, date string, flight vueloArchivado) {
 	if fmap[origin] == nil {
 		fmap[origin] = map[string]map[string][]vueloArchivado{}
@@ -405,7 +644,7 @@ func containsString(elms []string, s string) bool {
 	return false
 }
 
-func processNotificationSettings(client *wingo.Client, settings []notifications.Setting,
+func processNotificationSettings(ctx context.Context, client *wingo.Client, settings []notifications.Setting,
 	savedFlights flightsMap, startDate, stopDate time.Time) error {
 
 	type getFlightScheduleTask struct{ origin, destination string }
@@ -415,7 +654,7 @@ func processNotificationSettings(client *wingo.Client, settings []notifications.
 
 	wg := syncbits.Workgroup(func() {
 		for t := range getFlightsScheduleTasksChan {
-			information, err := client.GetFlightScheduleInformation(t.origin, t.destination, date.Format(startDate), date.Format(stopDate))
+			information, err := client.GetFlightScheduleInformation(ctx, t.origin, t.destination, date.Format(startDate), date.Format(stopDate))
 			if err != nil {
 				log.Println(err)
 				continue
@@ -449,7 +688,7 @@ func processNotificationSettings(client *wingo.Client, settings []notifications.
 	for _, flightInf := range flightsInformation {
 		flight := vueloArchivado{
 			Vuelo: wingo.Vuelo{
-				FlightNumber: cleanFlightNumber(flightInf.FlightNumber),
+				FlightNumber: wingo.CleanFlightNumber(flightInf.FlightNumber),
 			},
 			Services: []wingo.Service{},
 		}
@@ -470,11 +709,12 @@ func processNotificationSettings(client *wingo.Client, settings []notifications.
 
 type getInformationFlightsTask struct {
 	origin, destination string
+	currency            string
 	startDate, endDate  time.Time
 	subs                []notifications.Setting
 }
 
-func sendRoutesPerDate(origin, destination string, startDate, stopDate time.Time, getInformationFlightsChan chan<- getInformationFlightsTask, subs []notifications.Setting) {
+func sendRoutesPerDate(origin, destination, cur string, startDate, stopDate time.Time, getInformationFlightsChan chan<- getInformationFlightsTask, subs []notifications.Setting) {
 	for startDate.Before(stopDate) || startDate.Equal(stopDate) {
 		endDate := startDate.AddDate(0, 1, 0)
 		if endDate.After(stopDate) {
@@ -483,6 +723,7 @@ func sendRoutesPerDate(origin, destination string, startDate, stopDate time.Time
 		getInformationFlightsChan <- getInformationFlightsTask{
 			origin:      origin,
 			destination: destination,
+			currency:    cur,
 			startDate:   startDate,
 			endDate:     endDate,
 			subs:        subs,
@@ -491,7 +732,80 @@ func sendRoutesPerDate(origin, destination string, startDate, stopDate time.Time
 	}
 }
 
-func loadRoutes(client *wingo.Client, path string) ([]wingo.Route, []wingo.Route, error) {
+type dateWindow struct {
+	start, stop time.Time
+}
+
+// subscriptionWindows returns the windows covering the dates of subs between
+// startDate and stopDate. Windows less than a month apart are merged, as they
+// are fetched by the same monthly request.
+func subscriptionWindows(subs []notifications.Setting, startDate, stopDate time.Time) []dateWindow {
+	windows := []dateWindow{}
+	for _, sub := range subs {
+		first, last, err := sub.DateRange()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		if first.Before(startDate) {
+			first = startDate
+		}
+		if last.After(stopDate) {
+			last = stopDate
+		}
+		if last.Before(first) {
+			continue
+		}
+
+		windows = append(windows, dateWindow{first, last})
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].start.Before(windows[j].start)
+	})
+
+	merged := []dateWindow{}
+	for _, w := range windows {
+		if n := len(merged); n != 0 && !w.start.After(merged[n-1].stop.AddDate(0, 1, 0)) {
+			if w.stop.After(merged[n-1].stop) {
+				merged[n-1].stop = w.stop
+			}
+			continue
+		}
+		merged = append(merged, w)
+	}
+
+	return merged
+}
+
+// sendSubscriptionRoutes sends the routes of subs, in their currencies, for
+// the windows covering the subscribed dates. It returns the number of routes
+// sent.
+func sendSubscriptionRoutes(subs []notifications.Setting, startDate, stopDate time.Time, getInformationFlightsChan chan<- getInformationFlightsTask) int {
+	routesCount := 0
+
+	for origin, originSubs := range notifications.GroupByRoute(subs) {
+		for destination, destinationSubs := range originSubs {
+			for cur, subs := range notifications.GroupByCurrency(destinationSubs) {
+				windows := subscriptionWindows(subs, startDate, stopDate)
+				if len(windows) == 0 {
+					continue
+				}
+
+				fmt.Println(origin, "=>", destination, cur)
+				routesCount++
+				for _, w := range windows {
+					sendRoutesPerDate(origin, destination, cur, w.start, w.stop, getInformationFlightsChan, subs)
+				}
+			}
+		}
+	}
+
+	return routesCount
+}
+
+func loadRoutes(ctx context.Context, client *wingo.Client, path string) ([]wingo.Route, []wingo.Route, error) {
 	var savedRoutes struct {
 		Response []wingo.Route `json:"response"`
 	}
@@ -500,10 +814,34 @@ func loadRoutes(client *wingo.Client, path string) ([]wingo.Route, []wingo.Route
 		log.Println(err)
 	}
 
-	routes, err := client.GetRoutesWithCache(path)
+	routes, err := client.GetRoutesWithCache(ctx, path)
 	return savedRoutes.Response, routes, err
 }
 
+func printMetrics(client *wingo.Client, path string) {
+	metrics := client.Metrics()
+	fmt.Println("Request Count:", metrics.Requests())
+	fmt.Println("Retries:", metrics.Retries())
+	fmt.Println("Failures:", metrics.Failures())
+	fmt.Println("Bytes:", humanize.Bytes(uint64(metrics.Bytes())))
+
+	if path == "" {
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "could not create metrics file:", err)
+		return
+	}
+	defer f.Close()
+
+	err = metrics.WritePrometheus(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "could not write metrics:", err)
+	}
+}
+
 func main() {
 	starttime := time.Now()
 	defer func() {
@@ -528,18 +866,65 @@ func main() {
 	fmt.Println("  Start:", date.Format(startDate), "End:", date.Format(stopDate))
 	fmt.Println("--------------------------------------")
 
-	client := wingo.NewClient(logger)
+	ctx := context.Background()
+	if timeout, err := time.ParseDuration(os.Getenv("WINGO_TIMEOUT")); err == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	client := wingo.NewClient(append(wingo.OptionsFromEnv(), wingo.WithLogger(logger))...)
+	defer printMetrics(client, os.Getenv("WINGO_METRICS_FILE"))
+
+	backend, err := storage.NewFromEnv(storage.KindLocal)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// closed once the digests and the history are written
+	defer func() {
+		err := storage.Close(backend)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+
+	// the history is saved once, after the digests are sent
+	defer func() {
+		err := notificationHistory.Save()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
 	defer func() {
-		fmt.Println("Request Count:", client.RequestCount)
+		err := notificationDigest.send(context.Background(), notifier)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
 	}()
 
-	subs, err := notifications.LoadAllSettings()
+	registry, err := notify.NewRegistryFromEnv(backend)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outbox := notify.NewOutbox(backend, registry)
+	delivered, err := outbox.Retry(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if delivered != 0 {
+		logger.Printf("%d pending notifications delivered", delivered)
+	}
+	notifier = outbox
+	notificationHistory = notifications.NewHistory(backend)
+
+	subs, err := notifications.LoadAllSettings(backend)
 	if err != nil {
 		log.Fatal(err)
 	}
 	subs = notifications.FilterConfirmed(subs)
 	subs = notifications.FilterBetweenDates(subs, startDate, stopDate)
-	if len(subs) == 0 {
+	subs, roundTripSubs := notifications.SplitRoundTrips(subs)
+	if len(subs) == 0 && len(roundTripSubs) == 0 {
 		fmt.Println("we just got nothing to do")
 		return
 	}
@@ -552,23 +937,34 @@ func main() {
 	if routesDir == "" {
 		routesDir = "./"
 	}
-	savedRoutes, routes, err := loadRoutes(client, routesDir+"routes.json")
+	savedRoutes, routes, err := loadRoutes(ctx, client, routesDir+"routes.json")
 	if err != nil {
 		log.Fatal(err)
 	}
+	subs = notifications.ExpandRoutes(subs, routes)
 
 	logger.Println("Cargando vuelos guardados")
-	savedFlights, err := loadSavedFlights(routesDir, savedRoutes, startDate, stopDate)
+	savedFlights, err := loadSavedFlights(backend, savedRoutes, startDate, stopDate)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	logger.Println("Subscriptions count:", len(subs))
 	if fast {
-		processNotificationSettings(client, subs, savedFlights, startDate, stopDate)
+		// the schedules have no prices to compare flexible subscriptions with
+		fixedSubs, _ := notifications.SplitFlexible(subs)
+		processNotificationSettings(ctx, client, fixedSubs, savedFlights, startDate, stopDate)
 		return
 	}
 
+	if len(roundTripSubs) != 0 {
+		logger.Println("Round trip subscriptions count:", len(roundTripSubs))
+		err = processRoundTrips(ctx, client, backend, roundTripSubs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
 	logger.Println("Rutas guardadas:", len(savedRoutes))
 	logger.Println("Routes from API:", len(routes))
 	logger.Println("Vuelos guardados:", len(savedFlights))
@@ -585,7 +981,7 @@ func main() {
 
 	wg := syncbits.Workgroup(func() {
 		for t := range getInformationFlightsChan {
-			tasks := getInformationFlightsMonthly(client, t.origin, t.destination, t.startDate, t.endDate)
+			tasks := getInformationFlightsMonthly(ctx, client, t.origin, t.destination, t.currency, t.startDate, t.endDate, maxPassengers(t.subs))
 			for _, t2 := range tasks {
 				if err := checkDate(t.startDate, t.endDate, t2.fecha); err != nil {
 					fmt.Fprintln(os.Stderr, err)
@@ -595,7 +991,7 @@ func main() {
 				for _, pt := range tasks {
 					found := false
 					for _, sub := range t.subs {
-						if sub.Date == pt.fecha {
+						if sub.MatchesDate(pt.fecha) {
 							found = true
 							break
 						}
@@ -611,40 +1007,24 @@ func main() {
 	}, maxWorkers)
 
 	if runSubs {
-		subsByRoute := notifications.GroupByRoute(subs)
-
-		for origin, originSubs := range subsByRoute {
-			for destination, subs := range originSubs {
-				var routeStartDate, routeStopDate *time.Time
-				for _, sub := range subs {
-					d := date.MustParse(sub.Date)
-
-					if routeStartDate == nil || d.Before(*routeStartDate) {
-						routeStartDate = &d
-					}
-
-					if routeStopDate == nil || d.After(*routeStopDate) {
-						routeStopDate = &d
-					}
-				}
-
-				if routeStartDate != nil && routeStopDate != nil {
-					fmt.Println(origin, "=>", destination)
-					routesCount++
-					sendRoutesPerDate(origin, destination, *routeStartDate, *routeStopDate, getInformationFlightsChan, subs)
-				} else {
-					fmt.Println("both dates are nil - should not happen")
-				}
-			}
-		}
+		routesCount += sendSubscriptionRoutes(subs, startDate, stopDate, getInformationFlightsChan)
 	} else {
 		for _, origin := range routes {
 			for _, destination := range origin.Routes {
 				fmt.Println(origin.Name, "=>", destination.Name)
 				routesCount++
-				sendRoutesPerDate(origin.Code, destination.Code, startDate, stopDate, getInformationFlightsChan, nil)
+				sendRoutesPerDate(origin.Code, destination.Code, currency.Default, startDate, stopDate, getInformationFlightsChan, nil)
 			}
 		}
+
+		// the full scan only covers the default currency
+		foreignSubs := []notifications.Setting{}
+		for _, sub := range subs {
+			if !currency.IsDefault(sub.Currency) {
+				foreignSubs = append(foreignSubs, sub)
+			}
+		}
+		routesCount += sendSubscriptionRoutes(foreignSubs, startDate, stopDate, getInformationFlightsChan)
 	}
 	close(getInformationFlightsChan)
 
@@ -663,20 +1043,19 @@ func main() {
 	wgArchive := syncbits.Workgroup(func() {
 		for task := range archiveTaskChan {
 			flight := vueloArchivado{
-				Vuelo:    task.vuelo,
-				Services: task.services,
+				Vuelo:       task.vuelo,
+				Services:    task.services,
+				Ancillaries: wingo.NewAncillaries(task.services),
+			}
+			if !currency.IsDefault(task.currency) {
+				flight.Currency = currency.Normalize(task.currency)
 			}
 
 			actualFlightsMutex.Lock()
 			addFlightToMap(actualFlights, task.origin, task.destination, task.fecha, flight)
 			actualFlightsMutex.Unlock()
 
-			// save to file
-			fname := fmt.Sprintf("%s/%s/%s/%s/%s.json", outdir, task.origin, task.destination, task.fecha, flight.FlightNumber)
-
-			_ = os.MkdirAll(filepath.Dir(fname), os.ModePerm)
-
-			err := saveToFile(fname, flight)
+			err := saveFlight(backend, task.origin, task.destination, task.fecha, flight)
 			if err != nil {
 				fmt.Fprintln(os.Stderr, err)
 			}
@@ -690,7 +1069,7 @@ func main() {
 
 	getPriceTaskChan := make(chan getPriceTask, maxWorkers)
 	threadsG := syncbits.Workgroup(func() {
-		retrieveServices(client, getPriceTaskChan, archiveTaskChan)
+		retrieveServices(ctx, client, getPriceTaskChan, archiveTaskChan)
 	}, maxWorkers)
 
 	for _, task := range getPriceTasks {
@@ -711,10 +1090,15 @@ func main() {
 	fmt.Println("----------------------------------")
 
 	if runSubs {
-		err = processUnavailableFlightsForSubs(subs, savedFlights, actualFlights)
+		err = processUnavailableFlightsForSubs(backend, subs, savedFlights, actualFlights)
 	} else {
-		err = processUnavailableFlights(subs, savedFlights, actualFlights)
+		err = processUnavailableFlights(backend, subs, savedFlights, actualFlights)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
+
+	err = processFlexibleSubscriptions(backend, subs, actualFlights)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	}
@@ -1,20 +1,25 @@
 package main
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"testing"
+	"time"
 
+	"github.com/fabianMendez/wingo"
+	"github.com/fabianMendez/wingo/pkg/date"
+	"github.com/fabianMendez/wingo/pkg/i18n"
+	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/notify"
+	"github.com/fabianMendez/wingo/pkg/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestCleanFlightNumber(t *testing.T) {
-	actual := cleanFlightNumber("P5-7013")
-	assert.Equal(t, actual, "7013")
-}
-
 func TestWetag(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -50,3 +55,205 @@ func wetag(buf []byte) string {
 
 	return fmt.Sprintf(`W/"%x-%s"`, len(buf), hash[0:27])
 }
+
+func TestSubscriptionWindows(t *testing.T) {
+	day := func(s string) time.Time { return date.MustParse(s) }
+	subs := []notifications.Setting{
+		{Date: "2022-03-01"},
+		{DateFrom: "2022-01-20", DateTo: "2022-02-05"},
+		{Date: "2022-07-01"},
+		{DateFrom: "2022-08-01", DateTo: "2023-01-31", Weekdays: []string{"friday"}},
+	}
+
+	windows := subscriptionWindows(subs, day("2022-01-25"), day("2022-09-01"))
+	assert.Equal(t, []dateWindow{
+		{day("2022-01-25"), day("2022-03-01")},
+		{day("2022-07-01"), day("2022-09-01")},
+	}, windows)
+}
+
+func TestCheapestFlight(t *testing.T) {
+	flight := func(number string, price float64) vueloArchivado {
+		return vueloArchivado{Vuelo: wingo.Vuelo{
+			FlightNumber: number,
+			InfoFares:    []wingo.InfoFare{{FareAdult: wingo.Fare{FareAmount: price}}},
+		}}
+	}
+	actualFlights := flightsMap{"BOG": {"CTG": {
+		"2022-12-01": {flight("7000", 90)},
+		"2022-12-02": {flight("7001", 120), flight("7002", 100)},
+		"2022-12-09": {flight("7003", 110)},
+	}}}
+
+	sub := notifications.Setting{Origin: "BOG", Destination: "CTG", DateFrom: "2022-12-01", DateTo: "2022-12-31", Weekdays: []string{"friday"}}
+	cheapest, found := cheapestFlight(sub, actualFlights)
+	require.True(t, found)
+	assert.Equal(t, "2022-12-02", cheapest.Date)
+	assert.Equal(t, "7002", cheapest.FlightNumber)
+	assert.Equal(t, 100.0, cheapest.Price)
+
+	sub.Weekdays = []string{"monday"}
+	_, found = cheapestFlight(sub, actualFlights)
+	assert.False(t, found)
+}
+
+func TestProcessFlightContinues(t *testing.T) {
+	sent := []string{}
+	registry := notify.NewRegistry()
+	registry.Register("test", notify.ChannelFunc(func(ctx context.Context, recipient notify.Recipient, event notify.Event) error {
+		if recipient.Email == "a@example.com" {
+			return errors.New("mailbox is full")
+		}
+		sent = append(sent, recipient.Email)
+		return nil
+	}))
+	notifier = registry
+	defer func() { notifier = nil }()
+
+	sub := func(uid, email string) notifications.Setting {
+		return notifications.Setting{UID: uid, Origin: "BOG", Destination: "CTG", Date: "2022-12-02", Email: email, Channels: []string{"test"}}
+	}
+	flight := vueloArchivado{Vuelo: wingo.Vuelo{
+		FlightNumber: "7002",
+		InfoFares:    []wingo.InfoFare{{FareAdult: wingo.Fare{FareAmount: 100}}},
+	}}
+
+	err := processFlight([]notifications.Setting{sub("abc", "a@example.com"), sub("def", "b@example.com")},
+		flightsMap{}, "2022-12-02", "BOG", "CTG", flight)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"b@example.com"}, sent, "a failure for a subscriber does not stop the others")
+}
+
+func TestProcessFlightBundleNotQuoted(t *testing.T) {
+	sent := []notify.Event{}
+	registry := notify.NewRegistry()
+	registry.Register("test", notify.ChannelFunc(func(ctx context.Context, recipient notify.Recipient, event notify.Event) error {
+		sent = append(sent, event)
+		return nil
+	}))
+	notifier = registry
+	defer func() { notifier = nil }()
+
+	sub := notifications.Setting{UID: "abc", Origin: "BOG", Destination: "CTG", Date: "2022-12-02", Bundle: wingo.BundleClassic, Channels: []string{"test"}}
+	flight := func(services []wingo.Service) vueloArchivado {
+		return vueloArchivado{
+			Vuelo: wingo.Vuelo{
+				FlightNumber: "7002",
+				InfoFares:    []wingo.InfoFare{{FareAdult: wingo.Fare{FareAmount: 100}}},
+			},
+			Services: services,
+		}
+	}
+	quoted := flight([]wingo.Service{{CodeType: "CLAS", Amount: 40}})
+	savedFlights := flightsMap{"BOG": {"CTG": {"2022-12-02": {flight(nil)}}}}
+
+	require.NoError(t, processFlight([]notifications.Setting{sub}, savedFlights, "2022-12-02", "BOG", "CTG", flight(nil)))
+	assert.Empty(t, sent, "the bundle is not quoted")
+
+	require.NoError(t, processFlight([]notifications.Setting{sub}, savedFlights, "2022-12-02", "BOG", "CTG", quoted))
+	require.Len(t, sent, 1)
+	assert.Equal(t, notify.EventNewFlight, sent[0].Type, "the bundle was not available before")
+	assert.Equal(t, 140.0, sent[0].NewPrice)
+}
+
+func TestProcessFlexibleSubscriptionsContinues(t *testing.T) {
+	actualFlights := flightsMap{"BOG": {"CTG": {
+		"2022-12-02": {vueloArchivado{Vuelo: wingo.Vuelo{
+			FlightNumber: "7002",
+			InfoFares:    []wingo.InfoFare{{FareAdult: wingo.Fare{FareAmount: 100}}},
+		}}},
+	}}}
+	sub := func(uid string) notifications.Setting {
+		return notifications.Setting{UID: uid, Origin: "BOG", Destination: "CTG", DateFrom: "2022-12-01", DateTo: "2022-12-31", Digest: true}
+	}
+
+	backend := storage.NewMemory()
+	require.NoError(t, backend.Write(flexiblePath("broken"), []byte("{"), ""))
+
+	err := processFlexibleSubscriptions(backend, []notifications.Setting{sub("broken"), sub("abc")}, actualFlights)
+	assert.Error(t, err)
+
+	_, found, err := loadCheapestFlight(backend, "abc")
+	require.NoError(t, err)
+	assert.True(t, found, "a failing subscription does not stop the others")
+	notificationDigest = newDigest()
+}
+
+func TestSendPriceChangedNotifications(t *testing.T) {
+	sent := []notify.Event{}
+	registry := notify.NewRegistry()
+	registry.Register("test", notify.ChannelFunc(func(ctx context.Context, recipient notify.Recipient, event notify.Event) error {
+		sent = append(sent, event)
+		return nil
+	}))
+	notifier = registry
+	notificationHistory = notifications.NewHistory(storage.NewMemory())
+	defer func() {
+		notifier = nil
+		notificationHistory = nil
+	}()
+
+	sub := notifications.Setting{UID: "abc", Origin: "BOG", Destination: "CTG", Date: "2022-12-02", Channels: []string{"test"}, MinChange: 10}
+	subs := []notifications.Setting{sub}
+
+	require.NoError(t, sendNewFlightNotification(subs, "BOG", "CTG", "2022-12-02", "7002", "COP", 100))
+	require.NoError(t, sendPriceChangedNotifications(subs, "BOG", "CTG", "2022-12-02", "7002", "COP", 100, 94))
+	require.Len(t, sent, 1, "the change is smaller than min_change")
+
+	require.NoError(t, sendPriceChangedNotifications(subs, "BOG", "CTG", "2022-12-02", "7002", "COP", 94, 88))
+	require.Len(t, sent, 2, "the changes since the last notified price add up")
+	assert.Equal(t, 100.0, sent[1].OldPrice)
+	assert.Equal(t, 88.0, sent[1].NewPrice)
+
+	require.NoError(t, sendPriceChangedNotifications(subs, "BOG", "CTG", "2022-12-02", "7002", "COP", 88, 95))
+	assert.Len(t, sent, 2)
+}
+
+func TestDigest(t *testing.T) {
+	d := newDigest()
+	first := notifications.Setting{UID: "abc", Email: "a@example.com", PhoneNumber: "+573001234567", Digest: true}
+	second := notifications.Setting{UID: "def", Email: "a@example.com", Digest: true}
+
+	d.add(first, notify.Event{Subject: "BOG-CTG", Message: "Precio actual: $100.", Link: "https://wingo.com/1"}, "first")
+	d.add(second, notify.Event{Subject: "BOG-SMR", Message: "El precio BAJÓ.", Details: []string{"Equipaje"}, Link: "https://wingo.com/2"}, "second")
+
+	emails := d.events[digestKey{recipient: notify.Recipient{Email: "a@example.com", Language: i18n.Spanish}, language: i18n.Spanish}]
+	require.Len(t, emails, 2)
+	require.Len(t, d.events[digestKey{recipient: notify.Recipient{PhoneNumber: "+573001234567", Language: i18n.Spanish}, language: i18n.Spanish}], 1)
+	assert.Equal(t, "✈️ Resumen de precios: 2 novedades", digestSubject(i18n.Spanish, emails))
+	assert.Equal(t, "✈️ Price summary: 1 update", digestSubject(i18n.English, emails[:1]))
+
+	event := notify.Event{Type: notify.EventDigest, Events: emails}
+	assert.Equal(t, "BOG-CTG\nPrecio actual: $100.\nhttps://wingo.com/1\n\nBOG-SMR\nEl precio BAJÓ.\nEquipaje\nhttps://wingo.com/2",
+		event.Text())
+
+	sent := []notify.Recipient{}
+	registry := notify.NewRegistry()
+	registry.Register("test", notify.ChannelFunc(func(ctx context.Context, recipient notify.Recipient, event notify.Event) error {
+		sent = append(sent, recipient)
+		return nil
+	}))
+	registry.SetDefaults([]string{"test"})
+
+	notificationHistory = notifications.NewHistory(storage.NewMemory())
+	defer func() { notificationHistory = nil }()
+
+	allowed, err := notificationHistory.Allow(first, "first")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, d.send(context.Background(), registry))
+	assert.Len(t, sent, 2)
+	assert.Empty(t, d.events)
+
+	allowed, err = notificationHistory.Allow(first, "first")
+	require.NoError(t, err)
+	assert.False(t, allowed, "the digested notifications are recorded once sent")
+}
+
+func TestServiceCacheKey(t *testing.T) {
+	key := serviceCacheKey("BOG", "CTG", "2026-12-01", 1, "cop")
+	assert.Equal(t, "BOG-CTG-2026-12-01-1-COP", key)
+	assert.NotEqual(t, key, serviceCacheKey("BOG", "CTG", "2026-12-01", 2, "COP"))
+	assert.NotEqual(t, key, serviceCacheKey("BOG", "CTG", "2026-12-02", 1, "COP"))
+}
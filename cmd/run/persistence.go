@@ -2,111 +2,119 @@ package main
 
 import (
 	"encoding/json"
-	"io/fs"
+	"fmt"
 	"os"
-	"path/filepath"
+	"path"
 	"sync"
 	"time"
 
 	"github.com/fabianMendez/wingo"
+	"github.com/fabianMendez/wingo/pkg/currency"
 	"github.com/fabianMendez/wingo/pkg/date"
+	"github.com/fabianMendez/wingo/pkg/storage"
 )
 
-func saveToFile(filename string, v interface{}) error {
-	b, err := json.MarshalIndent(v, "", "  ")
+func loadFromFile(filename string, v interface{}) error {
+	f, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, b, os.ModePerm)
+	defer f.Close()
+
+	return json.NewDecoder(f).Decode(v)
 }
 
-func loadFromFile(filename string, v interface{}) error {
-	f, err := os.Open(filename)
+// archiveName is the name of the archive of a flight priced in cur. Flights
+// priced in the default currency keep their flight number as name.
+func archiveName(flightNumber, cur string) string {
+	if currency.IsDefault(cur) {
+		return flightNumber
+	}
+	return flightNumber + "." + currency.Normalize(cur)
+}
+
+func flightPath(origin, destination, date, flightNumber, cur string) string {
+	return path.Join(outdir, origin, destination, date, archiveName(flightNumber, cur)+".json")
+}
+
+func saveFlight(backend storage.Backend, origin, destination, date string, flight vueloArchivado) error {
+	b, err := json.MarshalIndent(flight, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return json.NewDecoder(f).Decode(v)
+	fname := flightPath(origin, destination, date, flight.FlightNumber, flight.Currency)
+	err = backend.Write(fname, b, fmt.Sprintf("update flight %s-%s/%s %s", origin, destination, date, flight.FlightNumber))
+	if err != nil {
+		return fmt.Errorf("could not save flight: %w", err)
+	}
+
+	return nil
 }
 
-func loadSavedFlights(dir string, savedRoutes []wingo.Route, startDate, stopDate time.Time) (flightsMap, error) {
+func deleteFlight(backend storage.Backend, origin, destination, date, flightNumber, cur string) error {
+	fname := flightPath(origin, destination, date, flightNumber, cur)
+	return backend.Delete(fname, fmt.Sprintf("remove flight %s-%s/%s %s", origin, destination, date, flightNumber))
+}
+
+func loadSavedFlights(backend storage.Backend, savedRoutes []wingo.Route, startDate, stopDate time.Time) (flightsMap, error) {
 	wg := new(sync.WaitGroup)
 	flightsMutex := new(sync.Mutex)
 	flights := flightsMap{}
 
 	for _, origin := range savedRoutes {
 		for _, destination := range origin.Routes {
-			dirname := filepath.Join(dir, outdir, origin.Code, destination.Code)
-			direntries, err := os.ReadDir(dirname)
+			dirname := path.Join(outdir, origin.Code, destination.Code)
+			dates, err := backend.List(dirname)
 			if err != nil {
-				if os.IsNotExist(err) {
-					continue
-				}
 				return nil, err
 			}
+			if len(dates) == 0 {
+				continue
+			}
 
 			wg.Add(1)
-			go func(origin, destination string, direntries []fs.DirEntry) {
+			go func(origin, destination string, dates []string) {
 				defer wg.Done()
 
-				for _, dentry := range direntries {
-					if !dentry.IsDir() {
+				for _, datestr := range dates {
+					date, err := date.Parse(datestr)
+					if err != nil {
 						continue
 					}
 
-					flightsMutex.Lock()
-					if flights[origin] == nil {
-						flights[origin] = map[string]map[string][]vueloArchivado{}
-					}
-
-					if flights[origin][destination] == nil {
-						flights[origin][destination] = map[string][]vueloArchivado{}
+					if date.Before(startDate) || date.After(stopDate) {
+						continue
 					}
-					flightsMutex.Unlock()
 
-					datepath := filepath.Join(dirname, dentry.Name())
-					fentries, err := os.ReadDir(datepath)
+					datepath := path.Join(dirname, datestr)
+					fnames, err := backend.List(datepath)
 					if err != nil {
-						// return nil, err
 						continue
 					}
 
-					for _, fentry := range fentries {
-						var varchivado vueloArchivado
-
-						flightpath := filepath.Join(datepath, fentry.Name())
-						f, err := os.Open(flightpath)
-						if err != nil {
-							// return nil, err
+					for _, fname := range fnames {
+						if path.Ext(fname) != ".json" {
 							continue
 						}
-						defer f.Close()
 
-						err = json.NewDecoder(f).Decode(&varchivado)
+						content, err := backend.Read(path.Join(datepath, fname))
 						if err != nil {
-							// return nil, err
 							continue
 						}
 
-						datestr := dentry.Name()
-						date, err := date.Parse(datestr)
+						var varchivado vueloArchivado
+						err = json.Unmarshal(content, &varchivado)
 						if err != nil {
 							continue
 						}
 
-						if date.Before(startDate) || date.After(stopDate) {
-							continue
-						}
-
 						flightsMutex.Lock()
-						flights[origin][destination][datestr] = append(
-							flights[origin][destination][datestr],
-							varchivado,
-						)
+						addFlightToMap(flights, origin, destination, datestr, varchivado)
 						flightsMutex.Unlock()
 					}
 				}
-			}(origin.Code, destination.Code, direntries)
+			}(origin.Code, destination.Code, dates)
 		}
 	}
 	wg.Wait()
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/fabianMendez/wingo"
+	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/storage"
+)
+
+const roundTripsDir = "roundtrips"
+
+func roundTripPath(origin, destination, date, returnDate string, passengers wingo.Passengers, cur string) string {
+	name := returnDate
+	if passengers != wingo.SingleAdult {
+		name += fmt.Sprintf("_%d-%d-%d", passengers.Adults, passengers.Children, passengers.Infants)
+	}
+	return path.Join(roundTripsDir, origin, destination, date, archiveName(name, cur)+".json")
+}
+
+func loadRoundTrip(backend storage.Backend, origin, destination, date, returnDate string, passengers wingo.Passengers, cur string) (viajeArchivado, bool, error) {
+	var viaje viajeArchivado
+
+	content, err := backend.Read(roundTripPath(origin, destination, date, returnDate, passengers, cur))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return viaje, false, nil
+		}
+		return viaje, false, err
+	}
+
+	err = json.Unmarshal(content, &viaje)
+	if err != nil {
+		return viaje, false, fmt.Errorf("could not decode round trip: %w", err)
+	}
+
+	return viaje, true, nil
+}
+
+func saveRoundTrip(backend storage.Backend, origin, destination, date, returnDate string, passengers wingo.Passengers, cur string, viaje viajeArchivado) error {
+	b, err := json.MarshalIndent(viaje, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fname := roundTripPath(origin, destination, date, returnDate, passengers, cur)
+	return backend.Write(fname, b, fmt.Sprintf("update round trip %s-%s/%s/%s", origin, destination, date, returnDate))
+}
+
+// legAdminFares returns the admin fares of a leg of a round trip, which are
+// charged for each of its flights.
+func legAdminFares(ctx context.Context, client *wingo.Client, date string, vuelo wingo.Vuelo, origin, destination, token, cur string) (float64, error) {
+	services, err := printInformation(ctx, client, date, vuelo, origin, destination, token, cur)
+	if err != nil {
+		return 0, err
+	}
+	return wingo.GetAdminFares(wingo.ServiceQuote{Services: services}), nil
+}
+
+func processRoundTrips(ctx context.Context, client *wingo.Client, backend storage.Backend, subs []notifications.Setting) error {
+	type trip struct {
+		origin, destination, date, returnDate string
+		passengers                            wingo.Passengers
+		currency                              string
+	}
+
+	trips := map[trip][]notifications.Setting{}
+	for _, sub := range subs {
+		t := trip{sub.Origin, sub.Destination, sub.Date, sub.ReturnDate, sub.GetPassengers(), sub.GetCurrency()}
+		trips[t] = append(trips[t], sub)
+	}
+
+	var firstErr error
+	for t, tripSubs := range trips {
+		information, err := client.SearchFlights(ctx, wingo.FlightSearch{
+			Origin:          t.origin,
+			Destination:     t.destination,
+			StartDate:       t.date,
+			ReturnStartDate: t.returnDate,
+			Passengers:      t.passengers,
+			Currency:        t.currency,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		previous, previousFound, err := loadRoundTrip(backend, t.origin, t.destination, t.date, t.returnDate, t.passengers, t.currency)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		ida, regreso, price, found := wingo.CheapestRoundTrip(information, t.date, t.returnDate, t.passengers)
+		if !found {
+			if previousFound {
+				_ = backend.Delete(roundTripPath(t.origin, t.destination, t.date, t.returnDate, t.passengers, t.currency), "remove round trip")
+				err = sendNotAvailableNotification(tripSubs, t.origin, t.destination, t.date, previous.Ida.FlightNumber, previous.Price)
+				keepFirstError(&firstErr, err)
+			}
+			continue
+		}
+
+		outboundFares, err := legAdminFares(ctx, client, t.date, ida, t.origin, t.destination, information.Token, t.currency)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		inboundFares, err := legAdminFares(ctx, client, t.returnDate, regreso, t.destination, t.origin, information.Token, t.currency)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		price += outboundFares + inboundFares
+
+		if err := saveRoundTrip(backend, t.origin, t.destination, t.date, t.returnDate, t.passengers, t.currency, viajeArchivado{Ida: ida, Regreso: regreso, Price: price}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		if !previousFound {
+			recipients := filterSubscriptions(tripSubs, func(sub notifications.Setting) bool {
+				return sub.Rule().ShouldNotifyNew(price)
+			})
+			err = sendNewFlightNotification(recipients, t.origin, t.destination, t.date, ida.FlightNumber, t.currency, price)
+		} else {
+			err = sendPriceChangedNotifications(tripSubs, t.origin, t.destination, t.date, ida.FlightNumber, t.currency, previous.Price, price)
+		}
+		keepFirstError(&firstErr, err)
+	}
+
+	return firstErr
+}
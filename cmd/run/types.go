@@ -6,12 +6,41 @@ type getPriceTask struct {
 	fecha               string
 	token               string
 	origin, destination string
+	currency            string
 	vuelo               wingo.Vuelo
 }
 
 type vueloArchivado struct {
 	wingo.Vuelo
-	Services []wingo.Service `json:"services"`
+	Services    []wingo.Service   `json:"services"`
+	Ancillaries wingo.Ancillaries `json:"ancillaries,omitempty"`
+	Currency    string            `json:"currency,omitempty"`
+}
+
+// GetAncillaries returns the archived ancillaries, or the ones derived from
+// the services for archives written before they were kept.
+func (v vueloArchivado) GetAncillaries() wingo.Ancillaries {
+	if v.Ancillaries != nil {
+		return v.Ancillaries
+	}
+	return wingo.NewAncillaries(v.Services)
+}
+
+// viajeArchivado is the cheapest round trip found for a subscription.
+type viajeArchivado struct {
+	Ida     wingo.Vuelo `json:"ida"`
+	Regreso wingo.Vuelo `json:"regreso"`
+	Price   float64     `json:"price"`
+}
+
+// vueloFlexible is the cheapest flight found for a subscription covering
+// several dates or airports.
+type vueloFlexible struct {
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	Date        string `json:"date"`
+	wingo.Vuelo
+	Price float64 `json:"price"`
 }
 
 // origin -> destination -> date -> flights
@@ -21,5 +50,6 @@ type archiveTask struct {
 	fecha               string
 	vuelo               wingo.Vuelo
 	origin, destination string
+	currency            string
 	services            []wingo.Service
 }
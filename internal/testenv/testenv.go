@@ -0,0 +1,21 @@
+// Package testenv holds the helpers shared by the tests of the module.
+package testenv
+
+import (
+	"os"
+	"testing"
+)
+
+// Setenv sets the environment variable key to value, restoring it when the
+// test finishes.
+func Setenv(t *testing.T, key, value string) {
+	previous, found := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if found {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package wingo
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// limiter throttles the requests sent to each host, both in requests per
+// second and in requests in flight at the same time.
+type limiter struct {
+	rps         float64
+	burst       int
+	maxInFlight int
+
+	mutex    *sync.Mutex
+	rates    map[string]*rate.Limiter
+	inFlight map[string]chan struct{}
+}
+
+func newLimiter(rps float64, burst, maxInFlight int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &limiter{
+		rps:         rps,
+		burst:       burst,
+		maxInFlight: maxInFlight,
+		mutex:       new(sync.Mutex),
+		rates:       map[string]*rate.Limiter{},
+		inFlight:    map[string]chan struct{}{},
+	}
+}
+
+func (l *limiter) forHost(host string) (*rate.Limiter, chan struct{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.rps > 0 && l.rates[host] == nil {
+		l.rates[host] = rate.NewLimiter(rate.Limit(l.rps), l.burst)
+	}
+
+	if l.maxInFlight > 0 && l.inFlight[host] == nil {
+		l.inFlight[host] = make(chan struct{}, l.maxInFlight)
+	}
+
+	return l.rates[host], l.inFlight[host]
+}
+
+// acquire blocks until a request to host is allowed. The returned function
+// must be called once the request is done.
+func (l *limiter) acquire(ctx context.Context, host string) (func(), error) {
+	rateLimiter, slots := l.forHost(host)
+
+	release := func() {}
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		once := new(sync.Once)
+		release = func() {
+			once.Do(func() { <-slots })
+		}
+	}
+
+	if rateLimiter != nil {
+		err := rateLimiter.Wait(ctx)
+		if err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+// releaseOnClose keeps the in flight slot of a request until its body is
+// closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
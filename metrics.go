@@ -0,0 +1,224 @@
+package wingo
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EndpointMetrics are the metrics recorded for the requests sent to a single
+// endpoint.
+type EndpointMetrics struct {
+	// Requests is the number of requests, regardless of how many attempts
+	// each one needed.
+	Requests    int
+	Retries     int
+	Failures    int
+	StatusCodes map[int]int
+	// Latency is the sum of the duration of every attempt.
+	Latency    time.Duration
+	MaxLatency time.Duration
+	Bytes      int64
+}
+
+type MetricsSnapshot struct {
+	Endpoints map[string]EndpointMetrics
+}
+
+func (s MetricsSnapshot) total() EndpointMetrics {
+	var total EndpointMetrics
+	for _, m := range s.Endpoints {
+		total.Requests += m.Requests
+		total.Retries += m.Retries
+		total.Failures += m.Failures
+		total.Latency += m.Latency
+		total.Bytes += m.Bytes
+		if m.MaxLatency > total.MaxLatency {
+			total.MaxLatency = m.MaxLatency
+		}
+	}
+	return total
+}
+
+func (s MetricsSnapshot) Requests() int { return s.total().Requests }
+func (s MetricsSnapshot) Retries() int  { return s.total().Retries }
+func (s MetricsSnapshot) Failures() int { return s.total().Failures }
+func (s MetricsSnapshot) Bytes() int64  { return s.total().Bytes }
+
+func (s MetricsSnapshot) endpoints() []string {
+	endpoints := make([]string, 0, len(s.Endpoints))
+	for endpoint := range s.Endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// WritePrometheus writes the snapshot using the Prometheus text exposition
+// format.
+func (s MetricsSnapshot) WritePrometheus(w io.Writer) error {
+	endpoints := s.endpoints()
+
+	counter := func(name, help string, value func(EndpointMetrics) string) error {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		if err != nil {
+			return err
+		}
+		for _, endpoint := range endpoints {
+			_, err = fmt.Fprintf(w, "%s{endpoint=%q} %s\n", name, endpoint, value(s.Endpoints[endpoint]))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := counter("wingo_requests_total", "Requests sent to the Wingo API.",
+		func(m EndpointMetrics) string { return fmt.Sprint(m.Requests) })
+	if err != nil {
+		return err
+	}
+
+	err = counter("wingo_retries_total", "Attempts retried after a failure.",
+		func(m EndpointMetrics) string { return fmt.Sprint(m.Retries) })
+	if err != nil {
+		return err
+	}
+
+	err = counter("wingo_failures_total", "Requests that failed after every attempt.",
+		func(m EndpointMetrics) string { return fmt.Sprint(m.Failures) })
+	if err != nil {
+		return err
+	}
+
+	err = counter("wingo_response_bytes_total", "Bytes read from response bodies.",
+		func(m EndpointMetrics) string { return fmt.Sprint(m.Bytes) })
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprint(w, "# HELP wingo_responses_total Responses received by status code.\n# TYPE wingo_responses_total counter\n")
+	if err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		m := s.Endpoints[endpoint]
+		codes := make([]int, 0, len(m.StatusCodes))
+		for code := range m.StatusCodes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+
+		for _, code := range codes {
+			_, err = fmt.Fprintf(w, "wingo_responses_total{endpoint=%q,code=\"%d\"} %d\n", endpoint, code, m.StatusCodes[code])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = fmt.Fprint(w, "# HELP wingo_request_duration_seconds Duration of the attempts sent to the Wingo API.\n# TYPE wingo_request_duration_seconds summary\n")
+	if err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		m := s.Endpoints[endpoint]
+		attempts := 0
+		for _, n := range m.StatusCodes {
+			attempts += n
+		}
+
+		_, err = fmt.Fprintf(w, "wingo_request_duration_seconds_sum{endpoint=%q} %g\nwingo_request_duration_seconds_count{endpoint=%q} %d\n",
+			endpoint, m.Latency.Seconds(), endpoint, attempts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type metrics struct {
+	mutex     *sync.Mutex
+	endpoints map[string]*EndpointMetrics
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		mutex:     new(sync.Mutex),
+		endpoints: map[string]*EndpointMetrics{},
+	}
+}
+
+func (m *metrics) update(endpoint string, fn func(*EndpointMetrics)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	em := m.endpoints[endpoint]
+	if em == nil {
+		em = &EndpointMetrics{StatusCodes: map[int]int{}}
+		m.endpoints[endpoint] = em
+	}
+	fn(em)
+}
+
+func (m *metrics) request(endpoint string) {
+	m.update(endpoint, func(em *EndpointMetrics) { em.Requests++ })
+}
+
+func (m *metrics) retry(endpoint string) {
+	m.update(endpoint, func(em *EndpointMetrics) { em.Retries++ })
+}
+
+func (m *metrics) failure(endpoint string) {
+	m.update(endpoint, func(em *EndpointMetrics) { em.Failures++ })
+}
+
+// attempt records an attempt that took latency. Attempts that did not get a
+// response are recorded with statusCode 0.
+func (m *metrics) attempt(endpoint string, statusCode int, latency time.Duration) {
+	m.update(endpoint, func(em *EndpointMetrics) {
+		em.StatusCodes[statusCode]++
+		em.Latency += latency
+		if latency > em.MaxLatency {
+			em.MaxLatency = latency
+		}
+	})
+}
+
+func (m *metrics) bytes(endpoint string, n int) {
+	m.update(endpoint, func(em *EndpointMetrics) { em.Bytes += int64(n) })
+}
+
+func (m *metrics) snapshot() MetricsSnapshot {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	snapshot := MetricsSnapshot{Endpoints: make(map[string]EndpointMetrics, len(m.endpoints))}
+	for endpoint, em := range m.endpoints {
+		copied := *em
+		copied.StatusCodes = make(map[int]int, len(em.StatusCodes))
+		for code, n := range em.StatusCodes {
+			copied.StatusCodes[code] = n
+		}
+		snapshot.Endpoints[endpoint] = copied
+	}
+	return snapshot
+}
+
+// meteredBody records the bytes read from a response body.
+type meteredBody struct {
+	io.ReadCloser
+	metrics  *metrics
+	endpoint string
+}
+
+func (b meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.metrics.bytes(b.endpoint, n)
+	}
+	return n, err
+}
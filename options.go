@@ -0,0 +1,216 @@
+package wingo
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	DefaultRoutesURL      = "https://routes-api.wingo.com"
+	DefaultAncillariesURL = "https://ancillaries-api.wingo.com"
+	DefaultTimeout        = 15 * time.Second
+)
+
+// Conservative limits used by OptionsFromEnv when the environment does not
+// set them.
+const (
+	DefaultRateLimit   = 2.0
+	DefaultRateBurst   = 2
+	DefaultMaxInFlight = 4
+)
+
+type options struct {
+	logger         *log.Logger
+	httpClient     *http.Client
+	transport      http.RoundTripper
+	proxy          func(*http.Request) (*url.URL, error)
+	tlsConfig      *tls.Config
+	timeout        time.Duration
+	headers        map[string]string
+	routesURL      string
+	ancillariesURL string
+	retryPolicy    RetryPolicy
+	rateLimit      float64
+	rateBurst      int
+	maxInFlight    int
+}
+
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		logger:    log.Default(),
+		tlsConfig: &tls.Config{},
+		timeout:   DefaultTimeout,
+		headers: map[string]string{
+			"User-Agent":      "Mozilla/5.0 (X11; Linux x86_64; rv:90.0) Gecko/20100101 Firefox/90.0",
+			"Origin":          "https://booking.wingo.com",
+			"Referer":         "https://booking.wingo.com/",
+			"Accept-Language": "en-US,en;q=0.5",
+		},
+		routesURL:      DefaultRoutesURL,
+		ancillariesURL: DefaultAncillariesURL,
+		retryPolicy:    DefaultRetryPolicy(),
+	}
+}
+
+func (o options) buildHTTPClient() *http.Client {
+	if o.httpClient != nil {
+		return o.httpClient
+	}
+
+	transport := o.transport
+	if transport == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		if o.proxy != nil {
+			t.Proxy = o.proxy
+			t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+		} else {
+			t.DialContext = proxy.Dial
+		}
+		t.TLSClientConfig = o.tlsConfig
+		transport = t
+	}
+
+	return &http.Client{Transport: transport, Timeout: o.timeout}
+}
+
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithHTTPClient makes the client send its requests through httpClient. The
+// transport, proxy, TLS and timeout options are ignored when it is used.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithTransport replaces the default transport. The proxy and TLS options are
+// ignored when it is used.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithProxy sends the requests through the given HTTP proxy instead of the
+// SOCKS proxy configured in the environment.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(o *options) {
+		o.proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// WithTLSVerification enables or disables the verification of the server
+// certificates. Verification is enabled by default.
+func WithTLSVerification(verify bool) Option {
+	return func(o *options) {
+		if o.tlsConfig == nil {
+			o.tlsConfig = &tls.Config{}
+		} else {
+			o.tlsConfig = o.tlsConfig.Clone()
+		}
+		o.tlsConfig.InsecureSkipVerify = !verify
+	}
+}
+
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithHeader adds a header to every request, replacing the default value
+// when there is one.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		headers := make(map[string]string, len(o.headers)+1)
+		for k, v := range o.headers {
+			headers[k] = v
+		}
+		headers[key] = value
+		o.headers = headers
+	}
+}
+
+// WithHeaders replaces the default headers sent with every request.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		o.headers = headers
+	}
+}
+
+func WithRoutesURL(u string) Option {
+	return func(o *options) {
+		o.routesURL = u
+	}
+}
+
+func WithAncillariesURL(u string) Option {
+	return func(o *options) {
+		o.ancillariesURL = u
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retryPolicy = policy
+	}
+}
+
+// WithRateLimit limits the requests sent to each host to rps requests per
+// second, allowing bursts of up to burst requests.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(o *options) {
+		o.rateLimit = rps
+		o.rateBurst = burst
+	}
+}
+
+// WithMaxInFlight limits the amount of concurrent requests to each host.
+func WithMaxInFlight(n int) Option {
+	return func(o *options) {
+		o.maxInFlight = n
+	}
+}
+
+// OptionsFromEnv returns the options configured with the WINGO_RATE_LIMIT,
+// WINGO_RATE_BURST and WINGO_MAX_IN_FLIGHT environment variables, using
+// DefaultRateLimit, DefaultRateBurst and DefaultMaxInFlight when they are not
+// set. Zero disables a limit.
+func OptionsFromEnv() []Option {
+	rps, err := strconv.ParseFloat(os.Getenv("WINGO_RATE_LIMIT"), 64)
+	if err != nil {
+		rps = DefaultRateLimit
+	}
+
+	burst, err := strconv.Atoi(os.Getenv("WINGO_RATE_BURST"))
+	if err != nil {
+		burst = DefaultRateBurst
+	}
+
+	n, err := strconv.Atoi(os.Getenv("WINGO_MAX_IN_FLIGHT"))
+	if err != nil {
+		n = DefaultMaxInFlight
+	}
+
+	return []Option{WithRateLimit(rps, burst), WithMaxInFlight(n)}
+}
@@ -0,0 +1,119 @@
+package wingo
+
+// Passengers is the amount of passengers of each type in a booking.
+type Passengers struct {
+	Adults   int `json:"adults"`
+	Children int `json:"children"`
+	Infants  int `json:"infants"`
+}
+
+// SingleAdult is the passenger mix used when none is given.
+var SingleAdult = Passengers{Adults: 1}
+
+func (p Passengers) IsZero() bool {
+	return p == Passengers{}
+}
+
+func (p Passengers) OrDefault() Passengers {
+	if p.IsZero() {
+		return SingleAdult
+	}
+	return p
+}
+
+func (p Passengers) Total() int {
+	return p.Adults + p.Children + p.Infants
+}
+
+// Max returns the largest amount of each passenger type of p and other.
+func (p Passengers) Max(other Passengers) Passengers {
+	if other.Adults > p.Adults {
+		p.Adults = other.Adults
+	}
+	if other.Children > p.Children {
+		p.Children = other.Children
+	}
+	if other.Infants > p.Infants {
+		p.Infants = other.Infants
+	}
+	return p
+}
+
+func sumarTarifa(fare Fare) float64 {
+	var taxes float64
+	for _, tax := range fare.ApplicableTaxes {
+		taxes += tax.TaxAmount
+	}
+	return fare.FareAmount + taxes
+}
+
+// fareAvailable reports whether fare was offered, which can not be told by
+// its price alone: promotional fares can be free.
+func fareAvailable(fare Fare) bool {
+	return fare.FareID != 0 || fare.FareAmount != 0 || len(fare.ApplicableTaxes) != 0
+}
+
+// SumarPrecioPasajeros returns the fares and taxes of every passenger in the
+// given mix, and whether every fare required by the mix is available.
+func SumarPrecioPasajeros(flight Vuelo, passengers Passengers) (float64, bool) {
+	if len(flight.InfoFares) == 0 {
+		return 0, false
+	}
+
+	passengers = passengers.OrDefault()
+	infoFare := flight.InfoFares[0]
+
+	var total float64
+	for _, item := range []struct {
+		count int
+		fare  Fare
+	}{
+		{passengers.Adults, infoFare.FareAdult},
+		{passengers.Children, infoFare.FareChild},
+		{passengers.Infants, infoFare.FareInfant},
+	} {
+		if item.count == 0 {
+			continue
+		}
+
+		if !fareAvailable(item.fare) {
+			return 0, false
+		}
+		total += float64(item.count) * sumarTarifa(item.fare)
+	}
+
+	return total, true
+}
+
+// SeatsAvailable returns the seats left at the cheapest fares of flight for
+// the given mix, or 0 when they are unknown. Infants do not take a seat.
+func SeatsAvailable(flight Vuelo, passengers Passengers) int64 {
+	if len(flight.InfoFares) == 0 {
+		return 0
+	}
+
+	passengers = passengers.OrDefault()
+	infoFare := flight.InfoFares[0]
+
+	seats := int64(-1)
+	for _, item := range []struct {
+		count int
+		fare  Fare
+	}{
+		{passengers.Adults, infoFare.FareAdult},
+		{passengers.Children, infoFare.FareChild},
+	} {
+		if item.count == 0 {
+			continue
+		}
+
+		if seats == -1 || item.fare.SeatsAvailable < seats {
+			seats = item.fare.SeatsAvailable
+		}
+	}
+	if seats == -1 {
+		return 0
+	}
+
+	return seats
+}
@@ -0,0 +1,52 @@
+package wingo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSumarPrecioPasajeros(t *testing.T) {
+	fare := func(amount, tax float64) Fare {
+		return Fare{FareAmount: amount, ApplicableTaxes: []FaretApplicableTax{{TaxAmount: tax}}}
+	}
+	flight := Vuelo{InfoFares: []InfoFare{{
+		FareAdult:  fare(100, 20),
+		FareChild:  fare(80, 10),
+		FareInfant: fare(10, 0),
+	}}}
+
+	tests := []struct {
+		name       string
+		flight     Vuelo
+		passengers Passengers
+		expected   float64
+		available  bool
+	}{
+		{name: "default", flight: flight, expected: 120, available: true},
+		{name: "family", flight: flight, passengers: Passengers{Adults: 2, Children: 1, Infants: 1}, expected: 240 + 90 + 10, available: true},
+		{name: "free fare", flight: Vuelo{InfoFares: []InfoFare{{FareAdult: Fare{FareID: 1}}}}, expected: 0, available: true},
+		{name: "missing fare", flight: Vuelo{InfoFares: []InfoFare{{FareAdult: fare(100, 20)}}}, passengers: Passengers{Adults: 1, Children: 1}},
+		{name: "no fares", flight: Vuelo{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, available := SumarPrecioPasajeros(tt.flight, tt.passengers)
+			assert.Equal(t, tt.expected, price)
+			assert.Equal(t, tt.available, available)
+		})
+	}
+}
+
+func TestSeatsAvailable(t *testing.T) {
+	flight := Vuelo{InfoFares: []InfoFare{{
+		FareAdult:  Fare{SeatsAvailable: 5},
+		FareChild:  Fare{SeatsAvailable: 3},
+		FareInfant: Fare{SeatsAvailable: 1},
+	}}}
+
+	assert.Equal(t, int64(5), SeatsAvailable(flight, Passengers{}))
+	assert.Equal(t, int64(3), SeatsAvailable(flight, Passengers{Adults: 1, Children: 1, Infants: 1}))
+	assert.Equal(t, int64(0), SeatsAvailable(Vuelo{}, SingleAdult))
+}
@@ -0,0 +1,56 @@
+package currency
+
+import (
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Default is the currency used when none is given.
+const Default = "COP"
+
+type format struct {
+	symbol  string
+	pattern string
+}
+
+var formats = map[string]format{
+	"COP": {symbol: "$", pattern: "#,###.##"},
+	"USD": {symbol: "US$", pattern: "#,###.##"},
+	"MXN": {symbol: "MX$", pattern: "#,###.##"},
+	"EUR": {symbol: "€", pattern: "#,###.##"},
+	"PAB": {symbol: "B/.", pattern: "#,###.##"},
+	"CUP": {symbol: "$MN", pattern: "#,###.##"},
+}
+
+// Normalize returns the upper case ISO 4217 code of c, or Default when c is
+// empty.
+func Normalize(c string) string {
+	c = strings.ToUpper(strings.TrimSpace(c))
+	if c == "" {
+		return Default
+	}
+	return c
+}
+
+func IsDefault(c string) bool {
+	return Normalize(c) == Default
+}
+
+func Symbol(c string) string {
+	c = Normalize(c)
+	if f, found := formats[c]; found {
+		return f.symbol
+	}
+	return c + " "
+}
+
+// Format renders amount with the symbol of currency c.
+func Format(amount float64, c string) string {
+	c = Normalize(c)
+	pattern := "#,###.##"
+	if f, found := formats[c]; found {
+		pattern = f.pattern
+	}
+	return Symbol(c) + humanize.FormatFloat(pattern, amount)
+}
@@ -0,0 +1,29 @@
+package currency_test
+
+import (
+	"testing"
+
+	"github.com/fabianMendez/wingo/pkg/currency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		expected string
+	}{
+		{name: "default", amount: 327203, currency: "", expected: "$327,203.00"},
+		{name: "pesos", amount: 327203, currency: "COP", expected: "$327,203.00"},
+		{name: "dollars", amount: 120.5, currency: "usd", expected: "US$120.50"},
+		{name: "mexican pesos", amount: 2500, currency: "MXN", expected: "MX$2,500.00"},
+		{name: "unknown", amount: 10, currency: "XYZ", expected: "XYZ 10.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, currency.Format(tt.amount, tt.currency))
+		})
+	}
+}
@@ -49,3 +49,23 @@ func MustParse(s string) time.Time {
 	}
 	return t
 }
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday, "domingo": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday, "lunes": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "martes": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "miercoles": time.Wednesday, "miércoles": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "jueves": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday, "viernes": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday, "sabado": time.Saturday, "sábado": time.Saturday,
+}
+
+// ParseWeekday parses the english or spanish name of a weekday, or its three
+// letter english abbreviation.
+func ParseWeekday(s string) (time.Weekday, error) {
+	weekday, found := weekdays[strings.ToLower(strings.TrimSpace(s))]
+	if !found {
+		return 0, fmt.Errorf("invalid weekday: %s", s)
+	}
+	return weekday, nil
+}
@@ -14,3 +14,14 @@ func TestParseEffectiveDate(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, time.Date(2022, time.March, 7, 0, 0, 0, 0, time.UTC), actual)
 }
+
+func TestParseWeekday(t *testing.T) {
+	for _, s := range []string{"friday", "Fri", " viernes "} {
+		actual, err := date.ParseWeekday(s)
+		require.NoError(t, err)
+		assert.Equal(t, time.Friday, actual)
+	}
+
+	_, err := date.ParseWeekday("someday")
+	assert.Error(t, err)
+}
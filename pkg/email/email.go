@@ -4,18 +4,28 @@ import (
 	"bytes"
 	"context"
 	"html/template"
-	"os"
+	"strings"
 
-	"github.com/mailgun/mailgun-go/v4"
+	"github.com/fabianMendez/wingo/pkg/i18n"
 )
 
 func BuildMessage(body string, data interface{}) (string, error) {
-	if data == nil {
+	return BuildLocalizedMessage(i18n.Default, body, data)
+}
+
+// BuildLocalizedMessage executes the template body, where the t function
+// returns the messages of the catalog in lang.
+func BuildLocalizedMessage(lang, body string, data interface{}) (string, error) {
+	if data == nil && !strings.Contains(body, "{{") {
 		return body, nil
 	}
 
 	buf := new(bytes.Buffer)
-	tpl := template.Must(template.New("email body").Parse(body))
+	tpl := template.Must(template.New("email body").Funcs(template.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			return i18n.T(lang, key, args...)
+		},
+	}).Parse(body))
 
 	err := tpl.Execute(buf, data)
 	if err != nil {
@@ -30,19 +40,10 @@ func SendMessage(ctx context.Context, subject, body string, data interface{}, to
 }
 
 func SendMessageWithText(ctx context.Context, subject, text, body string, data interface{}, to ...string) error {
-	mg, err := mailgun.NewMailgunFromEnv()
+	sender, err := NewSenderFromEnv()
 	if err != nil {
 		return err
 	}
 
-	html, err := BuildMessage(body, data)
-	if err != nil {
-		return err
-	}
-
-	msg := mg.NewMessage(os.Getenv("MG_FROM"), subject, text, to...)
-	msg.SetHtml(html)
-
-	_, _, err = mg.Send(ctx, msg)
-	return err
+	return Send(ctx, sender, i18n.Default, subject, text, body, data, to...)
 }
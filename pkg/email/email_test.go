@@ -0,0 +1,34 @@
+package email_test
+
+import (
+	"testing"
+
+	"github.com/fabianMendez/wingo/pkg/email"
+	"github.com/fabianMendez/wingo/pkg/i18n"
+	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildLocalizedMessage(t *testing.T) {
+	data := map[string]interface{}{"Message": "Current price: $100.", "Link": "https://wingo.com/1"}
+
+	body, err := email.BuildLocalizedMessage(i18n.English, email.TplPriceChange, data)
+	require.NoError(t, err)
+	assert.Contains(t, body, "View on Wingo")
+	assert.Contains(t, body, "Cancel subscription")
+	assert.NotContains(t, body, "Ver en Wingo")
+
+	body, err = email.BuildMessage(email.TplPriceChange, data)
+	require.NoError(t, err)
+	assert.Contains(t, body, "Ver en Wingo")
+
+	setting := notifications.Setting{Origin: "BOG", Destination: "CTG", Date: "2026-12-01"}
+	body, err = email.BuildLocalizedMessage(i18n.English, email.TplConfirmSubscription, map[string]interface{}{
+		"subscription": setting,
+		"link":         "https://example.com/confirm",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, body, "for the route BOG -&gt; CTG on 2026-12-01:")
+	assert.Contains(t, body, "Confirm your subscription")
+}
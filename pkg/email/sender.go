@@ -0,0 +1,75 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mailgun/mailgun-go/v4"
+)
+
+const (
+	ProviderMailgun = "mailgun"
+	ProviderSMTP    = "smtp"
+)
+
+// Message is an email with both a text and an HTML body.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers email messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// MailgunSender sends the messages through Mailgun, configured by the
+// environment variables read by mailgun.NewMailgunFromEnv.
+type MailgunSender struct{}
+
+func (MailgunSender) Send(ctx context.Context, msg Message) error {
+	mg, err := mailgun.NewMailgunFromEnv()
+	if err != nil {
+		return err
+	}
+
+	from := msg.From
+	if from == "" {
+		from = os.Getenv("MG_FROM")
+	}
+
+	m := mg.NewMessage(from, msg.Subject, msg.Text, msg.To...)
+	m.SetHtml(msg.HTML)
+
+	_, _, err = mg.Send(ctx, m)
+	return err
+}
+
+// NewSenderFromEnv returns the sender chosen by EMAIL_PROVIDER, Mailgun by
+// default.
+func NewSenderFromEnv() (Sender, error) {
+	switch provider := strings.ToLower(os.Getenv("EMAIL_PROVIDER")); provider {
+	case "", ProviderMailgun:
+		return MailgunSender{}, nil
+	case ProviderSMTP:
+		return NewSMTPSenderFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown email provider: %s", provider)
+	}
+}
+
+// Send builds the HTML body in lang from the template body and sends it
+// with sender.
+func Send(ctx context.Context, sender Sender, lang, subject, text, body string, data interface{}, to ...string) error {
+	html, err := BuildLocalizedMessage(lang, body, data)
+	if err != nil {
+		return err
+	}
+
+	return sender.Send(ctx, Message{To: to, Subject: subject, Text: text, HTML: html})
+}
@@ -0,0 +1,261 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// SecurityStartTLS upgrades the connection with STARTTLS, failing if the
+	// server does not support it.
+	SecurityStartTLS = "starttls"
+	// SecurityTLS connects with implicit TLS, usually on port 465.
+	SecurityTLS = "tls"
+	// SecurityNone sends the messages unencrypted, e.g. to a local sink.
+	SecurityNone = "none"
+)
+
+// SMTPSender sends the messages through an SMTP server.
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	Security string
+}
+
+// validateSecurity rejects the unknown security modes, which would send the
+// messages unencrypted.
+func validateSecurity(security string) error {
+	switch security {
+	case SecurityStartTLS, SecurityTLS, SecurityNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown smtp security: %q (use %s, %s or %s)", security, SecurityStartTLS, SecurityTLS, SecurityNone)
+	}
+}
+
+func NewSMTPSenderFromEnv() (SMTPSender, error) {
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	security := strings.ToLower(strings.TrimSpace(os.Getenv("SMTP_SECURITY")))
+	if security == "" {
+		security = SecurityStartTLS
+		if port == "465" {
+			security = SecurityTLS
+		}
+	}
+	err := validateSecurity(security)
+	if err != nil {
+		return SMTPSender{}, err
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("MG_FROM")
+	}
+
+	return SMTPSender{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+		Security: security,
+	}, nil
+}
+
+func (s SMTPSender) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.Host, s.Port)
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	if s.Security == SecurityTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.Host}}
+		return tlsDialer.DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (s SMTPSender) Send(ctx context.Context, msg Message) error {
+	err := validateSecurity(s.Security)
+	if err != nil {
+		return err
+	}
+
+	if msg.From == "" {
+		msg.From = s.From
+	}
+
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("could not parse sender: %w", err)
+	}
+
+	body, err := buildMIMEMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("could not connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("could not connect to smtp server: %w", err)
+	}
+	defer c.Close()
+
+	if s.Security == SecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", s.Host)
+		}
+
+		err = c.StartTLS(&tls.Config{ServerName: s.Host})
+		if err != nil {
+			return fmt.Errorf("could not start tls: %w", err)
+		}
+	}
+
+	if s.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host))
+		if err != nil {
+			return fmt.Errorf("could not authenticate: %w", err)
+		}
+	}
+
+	err = c.Mail(from.Address)
+	if err != nil {
+		return fmt.Errorf("could not set sender: %w", err)
+	}
+
+	for _, to := range msg.To {
+		address, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("could not parse recipient: %w", err)
+		}
+
+		err = c.Rcpt(address.Address)
+		if err != nil {
+			return fmt.Errorf("could not add recipient %s: %w", address.Address, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("could not send message: %w", err)
+	}
+
+	_, err = w.Write(body)
+	if err != nil {
+		return fmt.Errorf("could not send message: %w", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("could not send message: %w", err)
+	}
+
+	return c.Quit()
+}
+
+var (
+	reTags       = regexp.MustCompile(`(?s)<style.*?</style>|<[^>]*>`)
+	reBlankLines = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// htmlToText returns a plain text version of an HTML body, used when the
+// message has no text.
+func htmlToText(body string) string {
+	text := html.UnescapeString(reTags.ReplaceAllString(body, "\n"))
+	return strings.TrimSpace(reBlankLines.ReplaceAllString(text, "\n\n"))
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// buildMIMEMessage encodes msg as a multipart/alternative message with a
+// text and an HTML part.
+func buildMIMEMessage(msg Message) ([]byte, error) {
+	text := msg.Text
+	if text == "" {
+		text = htmlToText(msg.HTML)
+	}
+
+	buf := new(bytes.Buffer)
+	mw := multipart.NewWriter(buf)
+
+	domain := "localhost"
+	if from, err := mail.ParseAddress(msg.From); err == nil {
+		if i := strings.LastIndex(from.Address, "@"); i != -1 {
+			domain = from.Address[i+1:]
+		}
+	}
+
+	fmt.Fprintf(buf, "From: %s\r\n", msg.From)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "Message-ID: <%s@%s>\r\n", randomID(), domain)
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+
+	parts := []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, part := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		_, err = qw.Write([]byte(part.content))
+		if err != nil {
+			return nil, err
+		}
+		err = qw.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := mw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
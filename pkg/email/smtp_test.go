@@ -0,0 +1,161 @@
+package email_test
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/fabianMendez/wingo/internal/testenv"
+	"github.com/fabianMendez/wingo/pkg/email"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sinkMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// startSink starts a minimal SMTP server that keeps the received message,
+// like MailHog.
+func startSink(t *testing.T) (string, <-chan sinkMessage) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	messages := make(chan sinkMessage, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+
+		var msg sinkMessage
+		reply("220 sink")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 sink")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 ok")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 ok")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				data := new(strings.Builder)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				msg.data = data.String()
+				reply("250 ok")
+			case cmd == "QUIT":
+				reply("221 bye")
+				messages <- msg
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return l.Addr().String(), messages
+}
+
+func TestSMTPSender(t *testing.T) {
+	addr, messages := startSink(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	sender := email.SMTPSender{Host: host, Port: port, From: "Wingo <noreply@example.com>", Security: email.SecurityNone}
+	err = email.Send(context.Background(), sender, "es", "✈️ BOG-CTG/2026-12-01", "Precio actual: $100.",
+		"<p>{{.Message}}</p>", map[string]string{"Message": "Precio actual: $100."}, "a@example.com")
+	require.NoError(t, err)
+
+	received := <-messages
+	assert.Equal(t, "noreply@example.com", received.from)
+	assert.Equal(t, []string{"a@example.com"}, received.to)
+
+	msg, err := mail.ReadMessage(strings.NewReader(received.data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "✈️ BOG-CTG/2026-12-01", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	bodies := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		bodies[strings.Split(part.Header.Get("Content-Type"), ";")[0]] = string(b)
+	}
+
+	assert.Equal(t, "Precio actual: $100.", bodies["text/plain"])
+	assert.Equal(t, "<p>Precio actual: $100.</p>", bodies["text/html"])
+}
+
+func TestSMTPSenderRequiresStartTLS(t *testing.T) {
+	addr, _ := startSink(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	sender := email.SMTPSender{Host: host, Port: port, From: "noreply@example.com", Security: email.SecurityStartTLS}
+	err = sender.Send(context.Background(), email.Message{To: []string{"a@example.com"}, HTML: "<p>hola</p>"})
+	assert.Error(t, err)
+}
+
+func TestNewSMTPSenderFromEnv(t *testing.T) {
+	testenv.Setenv(t, "SMTP_PORT", "465")
+	testenv.Setenv(t, "SMTP_SECURITY", "")
+	sender, err := email.NewSMTPSenderFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, email.SecurityTLS, sender.Security)
+
+	testenv.Setenv(t, "SMTP_SECURITY", "STARTTLS ")
+	sender, err = email.NewSMTPSenderFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, email.SecurityStartTLS, sender.Security)
+
+	testenv.Setenv(t, "SMTP_SECURITY", "ssl")
+	_, err = email.NewSMTPSenderFromEnv()
+	assert.Error(t, err, "unknown modes are not sent unencrypted")
+
+	err = email.SMTPSender{Host: "localhost", Port: "25", Security: "ssl"}.Send(context.Background(), email.Message{From: "noreply@example.com"})
+	assert.Error(t, err)
+}
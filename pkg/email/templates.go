@@ -111,7 +111,7 @@ const TplCancelSubscription = `
     <table border="0" cellpadding="0" cellspacing="0" role="presentation" style="border-collapse:separate;line-height:100%;">
       <tr>
         <td align="center" bgcolor="#D1D5DB" role="presentation" style="border:none;border-radius:3px;cursor:auto;mso-padding-alt:10px 25px;background:#D1D5DB;" valign="middle">
-          <a href="{{.CancelSubscriptionLink}}" style="display:inline-block;background:#D1D5DB;color:#134E4A;font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;font-weight:normal;line-height:120%;margin:0;text-decoration:none;text-transform:none;padding:10px 25px;mso-padding-alt:0px;border-radius:3px;" target="_blank"> Cancelar suscripción </a>
+          <a href="{{.CancelSubscriptionLink}}" style="display:inline-block;background:#D1D5DB;color:#134E4A;font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;font-weight:normal;line-height:120%;margin:0;text-decoration:none;text-transform:none;padding:10px 25px;mso-padding-alt:0px;border-radius:3px;" target="_blank"> {{t "link.cancel"}} </a>
         </td>
       </tr>
     </table>
@@ -141,12 +141,19 @@ const TplPriceChange = TplPreffix + `
 	<div style="font-family:Helvetica;font-size:18px;font-weight:bold;line-height:1;text-align:left;color:#4B5563;">{{.Message}}</div>
   </td>
 </tr>
+{{range .Details}}
+<tr>
+  <td align="left" style="font-size:0px;padding:5px 25px;word-break:break-word;">
+	<div style="font-family:Helvetica;font-size:15px;line-height:1.3;text-align:left;color:#4B5563;">{{.}}</div>
+  </td>
+</tr>
+{{end}}
 <tr>
   <td align="center" vertical-align="middle" style="font-size:0px;padding:10px 25px;word-break:break-word;">
 	<table border="0" cellpadding="0" cellspacing="0" role="presentation" style="border-collapse:separate;line-height:100%;">
 	  <tr>
       <td align="center" bgcolor="#4068E0" role="presentation" style="border:none;border-radius:3px;cursor:auto;mso-padding-alt:10px 25px;background:#4068E0;" valign="middle">
-        <a href="{{.LinkHistory}}" style="display:inline-block;background:#4068E0;color:#ffffff;font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;font-weight:bold;line-height:120%;margin:0;text-decoration:none;text-transform:none;padding:10px 25px;mso-padding-alt:0px;border-radius:3px;" target="_blank">Historial</a>
+        <a href="{{.LinkHistory}}" style="display:inline-block;background:#4068E0;color:#ffffff;font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;font-weight:bold;line-height:120%;margin:0;text-decoration:none;text-transform:none;padding:10px 25px;mso-padding-alt:0px;border-radius:3px;" target="_blank">{{t "link.history"}}</a>
       </td>
     </tr>
   </table>
@@ -157,7 +164,7 @@ const TplPriceChange = TplPreffix + `
 	<table border="0" cellpadding="0" cellspacing="0" role="presentation" style="border-collapse:separate;line-height:100%;">
 	  <tr>
 		<td align="center" bgcolor="#14B8A6" role="presentation" style="border:none;border-radius:3px;cursor:auto;mso-padding-alt:10px 25px;background:#14B8A6;" valign="middle">
-		  <a href="{{.Link}}" style="display:inline-block;background:#14B8A6;color:#ffffff;font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;font-weight:bold;line-height:120%;margin:0;text-decoration:none;text-transform:none;padding:10px 25px;mso-padding-alt:0px;border-radius:3px;" target="_blank">Ver en Wingo</a>
+		  <a href="{{.Link}}" style="display:inline-block;background:#14B8A6;color:#ffffff;font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;font-weight:bold;line-height:120%;margin:0;text-decoration:none;text-transform:none;padding:10px 25px;mso-padding-alt:0px;border-radius:3px;" target="_blank">{{t "link.flight"}}</a>
 		</td>
 	  </tr>
 	</table>
@@ -170,13 +177,13 @@ const TplConfirmSubscription = TplPreffix + `
 <tbody>
   <tr>
     <td align="left" style="font-size:0px;padding:10px 25px;word-break:break-word;">
-      <div style="font-family:Helvetica;font-size:26px;font-weight:bolder;line-height:1;text-align:left;color:#111827;">Confirma tu suscripción</div>
+      <div style="font-family:Helvetica;font-size:26px;font-weight:bolder;line-height:1;text-align:left;color:#111827;">{{t "confirm.title"}}</div>
     </td>
   </tr>
   <tr>
     <td align="left" style="font-size:0px;padding:10px 25px;word-break:break-word;">
       <div style="font-family:Helvetica;font-size:18px;line-height:1;text-align:left;color:#4B5563;">
-      Usa el siguiente link para confirmar tu suscripción para recibir notificaciones sobre actualizaciones del precio de la ruta {{.subscription.Origin}} -> {{.subscription.Destination}} el {{.subscription.Date}}:
+      {{t "confirm.body" .subscription.Origin .subscription.Destination .subscription.DateLabel}}
       </div>
     </td>
   </tr>
@@ -186,13 +193,22 @@ const TplConfirmSubscription = TplPreffix + `
         <tr>
           <td align="center" bgcolor="#14B8A6" role="presentation" style="border:none;border-radius:3px;cursor:auto;mso-padding-alt:10px 25px;background:#14B8A6;" valign="middle">
             <a href="{{.link}}" style="display:inline-block;background:#14B8A6;color:#ffffff;font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;font-weight:bold;line-height:120%;margin:0;text-decoration:none;text-transform:none;padding:10px 25px;mso-padding-alt:0px;border-radius:3px;" target="_blank">
-            Confirmar
+            {{t "confirm.button"}}
             </a>
           </td>
         </tr>
       </table>
     </td>
   </tr>
+  {{if .telegram_link}}
+  <tr>
+    <td align="left" style="font-size:0px;padding:10px 25px;word-break:break-word;">
+      <div style="font-family:Helvetica;font-size:18px;line-height:1;text-align:left;color:#4B5563;">
+      {{t "confirm.telegram"}} <a href="{{.telegram_link}}" target="_blank">Telegram</a>
+      </div>
+    </td>
+  </tr>
+  {{end}}
   <tr>
     <td align="center" style="font-size:0px;padding:10px 25px;word-break:break-word;">
       <p style="border-top:dashed 1px lightgrey;font-size:1px;margin:0px auto;width:100%;">
@@ -204,48 +220,53 @@ const TplConfirmSubscription = TplPreffix + `
   <tr>
     <td align="left" style="font-size:0px;padding:10px 25px;word-break:break-word;">
       <div style="font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;line-height:1;text-align:left;color:#4B5563;">
-        Si no solicitaste esta suscripción, por favor ignora este mensaje.
+        {{t "confirm.ignore"}}
       </div>
     </td>
   </tr>
 </tbody>
 ` + TplSuffix
 
-const TplConfirmSubscriptionEn = TplPreffix + `
+const TplDigest = TplPreffix + `
 <tbody>
-  <tr>
-    <td align="left" style="font-size:0px;padding:10px 25px;word-break:break-word;">
-      <div style="font-family:Helvetica;font-size:26px;font-weight:bolder;line-height:1;text-align:left;color:#111827;">Confirm your subscription</div>
-    </td>
-  </tr>
-  <tr>
-    <td align="left" style="font-size:0px;padding:10px 25px;word-break:break-word;">
-      <div style="font-family:Helvetica;font-size:18px;line-height:1;text-align:left;color:#4B5563;">Use the following link to confirm your subscription to receive notifications about price updates for the route {{.subscription.Origin}} -> {{.subscription.Destination}} on {{.subscription.Date}}:</div>
-    </td>
-  </tr>
-  <tr>
-    <td align="center" vertical-align="middle" style="font-size:0px;padding:10px 25px;word-break:break-word;">
-      <table border="0" cellpadding="0" cellspacing="0" role="presentation" style="border-collapse:separate;line-height:100%;">
-        <tr>
-          <td align="center" bgcolor="#14B8A6" role="presentation" style="border:none;border-radius:3px;cursor:auto;mso-padding-alt:10px 25px;background:#14B8A6;" valign="middle">
-            <a href="{{.link}}" style="display:inline-block;background:#14B8A6;color:#ffffff;font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;font-weight:bold;line-height:120%;margin:0;text-decoration:none;text-transform:none;padding:10px 25px;mso-padding-alt:0px;border-radius:3px;" target="_blank"> Confirm </a>
-          </td>
-        </tr>
-      </table>
-    </td>
-  </tr>
-  <tr>
-    <td align="center" style="font-size:0px;padding:10px 25px;word-break:break-word;">
-      <p style="border-top:dashed 1px lightgrey;font-size:1px;margin:0px auto;width:100%;">
-      </p>
-      <!--[if mso | IE]><table align="center" border="0" cellpadding="0" cellspacing="0" style="border-top:dashed 1px lightgrey;font-size:1px;margin:0px auto;width:550px;" role="presentation" width="550px" ><tr><td style="height:0;line-height:0;"> &nbsp;
-</td></tr></table><![endif]-->
-    </td>
-  </tr>
-  <tr>
-    <td align="left" style="font-size:0px;padding:10px 25px;word-break:break-word;">
-      <div style="font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;line-height:1;text-align:left;color:#4B5563;">If you did not request this subscription, please ignore this message.</div>
-    </td>
-  </tr>
+<tr>
+  <td align="left" style="font-size:0px;padding:10px 25px;word-break:break-word;">
+	<div style="font-family:Helvetica;font-size:26px;font-weight:bolder;line-height:1;text-align:left;color:#111827;">{{t "digest.title"}}</div>
+  </td>
+</tr>
+{{range .Items}}
+<tr>
+  <td align="left" style="font-size:0px;padding:10px 25px 0px;word-break:break-word;">
+	<div style="font-family:Helvetica;font-size:18px;font-weight:bold;line-height:1.3;text-align:left;color:#111827;">{{.Subject}}</div>
+  </td>
+</tr>
+<tr>
+  <td align="left" style="font-size:0px;padding:5px 25px;word-break:break-word;">
+	<div style="font-family:Helvetica;font-size:16px;font-weight:bold;line-height:1.3;text-align:left;color:#4B5563;">{{.Message}}</div>
+  </td>
+</tr>
+{{range .Details}}
+<tr>
+  <td align="left" style="font-size:0px;padding:5px 25px;word-break:break-word;">
+	<div style="font-family:Helvetica;font-size:15px;line-height:1.3;text-align:left;color:#4B5563;">{{.}}</div>
+  </td>
+</tr>
+{{end}}
+<tr>
+  <td align="left" style="font-size:0px;padding:5px 25px 10px;word-break:break-word;">
+	<div style="font-family:Ubuntu, Helvetica, Arial, sans-serif;font-size:13px;line-height:1.3;text-align:left;">
+	  <a href="{{.LinkHistory}}" style="color:#4068E0;" target="_blank">{{t "link.history"}}</a> ·
+	  <a href="{{.Link}}" style="color:#14B8A6;" target="_blank">{{t "link.flight"}}</a> ·
+	  <a href="{{.CancelSubscriptionLink}}" style="color:#6B7280;" target="_blank">{{t "link.cancel"}}</a>
+	</div>
+  </td>
+</tr>
+<tr>
+  <td align="center" style="font-size:0px;padding:10px 25px;word-break:break-word;">
+    <p style="border-top:dashed 1px lightgrey;font-size:1px;margin:0px auto;width:100%;">
+    </p>
+  </td>
+</tr>
+{{end}}
 </tbody>
 ` + TplSuffix
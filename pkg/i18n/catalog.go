@@ -0,0 +1,72 @@
+package i18n
+
+var catalog = map[string]map[string]string{
+	Spanish: {
+		"price.current":         "Precio actual: %s.",
+		"price.up":              "↗️ El precio SUBIÓ a %s (desde %s).",
+		"price.down":            "↘️ El precio BAJÓ a %s (desde %s).",
+		"flight.not_available":  "El vuelo ya NO está disponible.",
+		"seats.low":             "💺 Quedan solo %d sillas a %s.",
+		"seats.low_one":         "💺 Queda solo 1 silla a %s.",
+		"baggage.changed":       "🧳 Cambiaron las tarifas de equipaje.",
+		"baggage.new":           "%s: %s (nuevo).",
+		"baggage.not_available": "%s: ya NO está disponible.",
+		"baggage.price":         "%s: %s (desde %s).",
+
+		"digest.title":       "Resumen de precios",
+		"digest.subject_one": "✈️ Resumen de precios: 1 novedad",
+		"digest.subject":     "✈️ Resumen de precios: %d novedades",
+
+		"link.history": "Historial",
+		"link.flight":  "Ver en Wingo",
+		"link.cancel":  "Cancelar suscripción",
+
+		"confirm.subject":  "Por favor confirma tu suscripción",
+		"confirm.title":    "Confirma tu suscripción",
+		"confirm.body":     "Usa el siguiente link para confirmar tu suscripción para recibir notificaciones sobre actualizaciones del precio de la ruta %s -> %s el %s:",
+		"confirm.button":   "Confirmar",
+		"confirm.ignore":   "Si no solicitaste esta suscripción, por favor ignora este mensaje.",
+		"confirm.telegram": "Para recibir las notificaciones por Telegram, abre este link:",
+
+		"subscription.not_found": "Suscripción no encontrada",
+		"subscription.confirmed": "La suscripción ha sido confirmada",
+		"subscription.cancelled": "La suscripción ha sido cancelada",
+
+		"telegram.chat_id":   "Tu chat ID es %s. Úsalo en tu suscripción para recibir las notificaciones por Telegram.",
+		"telegram.confirmed": "La suscripción a la ruta %s -> %s el %s ha sido confirmada.",
+	},
+	English: {
+		"price.current":         "Current price: %s.",
+		"price.up":              "↗️ The price went UP to %s (from %s).",
+		"price.down":            "↘️ The price went DOWN to %s (from %s).",
+		"flight.not_available":  "The flight is NO longer available.",
+		"seats.low":             "💺 Only %d seats left at %s.",
+		"seats.low_one":         "💺 Only 1 seat left at %s.",
+		"baggage.changed":       "🧳 The baggage fees changed.",
+		"baggage.new":           "%s: %s (new).",
+		"baggage.not_available": "%s: NO longer available.",
+		"baggage.price":         "%s: %s (from %s).",
+
+		"digest.title":       "Price summary",
+		"digest.subject_one": "✈️ Price summary: 1 update",
+		"digest.subject":     "✈️ Price summary: %d updates",
+
+		"link.history": "History",
+		"link.flight":  "View on Wingo",
+		"link.cancel":  "Cancel subscription",
+
+		"confirm.subject":  "Please confirm your subscription",
+		"confirm.title":    "Confirm your subscription",
+		"confirm.body":     "Use the following link to confirm your subscription to receive notifications about price updates for the route %s -> %s on %s:",
+		"confirm.button":   "Confirm",
+		"confirm.ignore":   "If you did not request this subscription, please ignore this message.",
+		"confirm.telegram": "To receive the notifications on Telegram, open this link:",
+
+		"subscription.not_found": "Subscription not found",
+		"subscription.confirmed": "The subscription has been confirmed",
+		"subscription.cancelled": "The subscription has been cancelled",
+
+		"telegram.chat_id":   "Your chat ID is %s. Use it in your subscription to receive the notifications on Telegram.",
+		"telegram.confirmed": "The subscription to the route %s -> %s on %s has been confirmed.",
+	},
+}
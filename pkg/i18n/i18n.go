@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	Spanish = "es"
+	English = "en"
+
+	Default = Spanish
+)
+
+// Languages are the supported languages.
+var Languages = []string{Spanish, English}
+
+// base returns the primary language of a language tag, e.g. "en" for
+// "en-US".
+func base(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i != -1 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// IsSupported reports whether lang is empty or a tag of a supported
+// language.
+func IsSupported(lang string) bool {
+	_, found := catalog[base(lang)]
+	return lang == "" || found
+}
+
+// Normalize returns the supported language of a language tag (e.g. "en-US"
+// is "en"), or Default.
+func Normalize(lang string) string {
+	if _, found := catalog[base(lang)]; found {
+		return base(lang)
+	}
+	return Default
+}
+
+// FromAcceptLanguage returns the first supported language of an
+// Accept-Language header, or Default.
+func FromAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.Split(part, ";")[0]
+		if _, found := catalog[base(tag)]; found {
+			return base(tag)
+		}
+	}
+	return Default
+}
+
+// T returns the message of key in lang formatted with args. Messages missing
+// in lang are taken from Default.
+func T(lang, key string, args ...interface{}) string {
+	msg, found := catalog[Normalize(lang)][key]
+	if !found {
+		msg, found = catalog[Default][key]
+	}
+	if !found {
+		msg = key
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Keys returns the keys of the messages of lang.
+func Keys(lang string) []string {
+	keys := make([]string, 0, len(catalog[lang]))
+	for key := range catalog[lang] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,42 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"github.com/fabianMendez/wingo/pkg/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, i18n.English, i18n.Normalize("en-US"))
+	assert.Equal(t, i18n.English, i18n.Normalize(" EN "))
+	assert.Equal(t, i18n.Spanish, i18n.Normalize("es_CO"))
+	assert.Equal(t, i18n.Default, i18n.Normalize(""))
+	assert.Equal(t, i18n.Default, i18n.Normalize("fr"))
+
+	assert.True(t, i18n.IsSupported(""))
+	assert.True(t, i18n.IsSupported("en-GB"))
+	assert.False(t, i18n.IsSupported("fr"))
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	assert.Equal(t, i18n.English, i18n.FromAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, es;q=0.7"))
+	assert.Equal(t, i18n.Spanish, i18n.FromAcceptLanguage("es-CO,es;q=0.9"))
+	assert.Equal(t, i18n.Default, i18n.FromAcceptLanguage("de"))
+	assert.Equal(t, i18n.Default, i18n.FromAcceptLanguage(""))
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Precio actual: $100.", i18n.T(i18n.Spanish, "price.current", "$100"))
+	assert.Equal(t, "Current price: $100.", i18n.T("en-US", "price.current", "$100"))
+	assert.Equal(t, "Precio actual: $100.", i18n.T("fr", "price.current", "$100"))
+	assert.Equal(t, "missing.key", i18n.T(i18n.English, "missing.key"))
+}
+
+func TestCatalogIsComplete(t *testing.T) {
+	for _, key := range i18n.Keys(i18n.Default) {
+		for _, lang := range i18n.Languages {
+			assert.Contains(t, i18n.Keys(lang), key, lang)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/fabianMendez/wingo"
+)
+
+// AirportGroups are the named groups of airports that can be used as origin or
+// destination of a subscription. The countries of the routes returned by
+// wingo.Client.GetRoutes can also be used as groups.
+var AirportGroups = map[string][]string{
+	"CARIBE":   {"ADZ", "BAQ", "CTG", "SMR"},
+	"ANTILLAS": {"AUA", "CUR"},
+}
+
+// RoutePair is a concrete route between two airports.
+type RoutePair struct {
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+}
+
+// SplitAirports returns the airports of a comma separated list or a named
+// group. Any other value is returned as a single airport.
+func SplitAirports(s string) []string {
+	if airports, found := AirportGroups[strings.ToUpper(strings.TrimSpace(s))]; found {
+		return airports
+	}
+
+	airports := []string{}
+	for _, code := range strings.Split(s, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" {
+			airports = append(airports, code)
+		}
+	}
+	return airports
+}
+
+// ResolveAirports returns the airports of s, which can also be a country of
+// the given routes.
+func ResolveAirports(s string, routes []wingo.Route) ([]string, error) {
+	country := []string{}
+	for _, route := range routes {
+		if strings.EqualFold(route.Country, strings.TrimSpace(s)) {
+			country = append(country, route.Code)
+		}
+	}
+	if len(country) != 0 {
+		sort.Strings(country)
+		return country, nil
+	}
+
+	airports := SplitAirports(s)
+	if len(airports) == 0 {
+		return nil, errors.New("no airports given")
+	}
+
+	for _, code := range airports {
+		if findRoute(routes, code) == nil {
+			return nil, fmt.Errorf("unknown airport: %s", code)
+		}
+	}
+	return airports, nil
+}
+
+func findRoute(routes []wingo.Route, code string) *wingo.Route {
+	for i := range routes {
+		if routes[i].Code == code {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+// isAirportGroup reports whether s is a list of airports, a named group or a
+// country, i.e. anything but a single airport code.
+func isAirportGroup(s string) bool {
+	airports := SplitAirports(s)
+	return len(airports) > 1 || (len(airports) == 1 && len(airports[0]) != 3)
+}
+
+// IsMultiAirport reports whether the origin or the destination of the
+// subscription is a group of airports.
+func (s Setting) IsMultiAirport() bool {
+	return len(s.Routes) != 0 || isAirportGroup(s.Origin) || isAirportGroup(s.Destination)
+}
+
+// ResolveRoutes returns the route pairs of the subscription operated by Wingo.
+func (s Setting) ResolveRoutes(routes []wingo.Route) ([]RoutePair, error) {
+	origins, err := ResolveAirports(s.Origin, routes)
+	if err != nil {
+		return nil, err
+	}
+	destinations, err := ResolveAirports(s.Destination, routes)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := []RoutePair{}
+	for _, origin := range origins {
+		route := findRoute(routes, origin)
+		for _, destination := range destinations {
+			if findRoute(route.Routes, destination) != nil {
+				pairs = append(pairs, RoutePair{origin, destination})
+			}
+		}
+	}
+
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("there are no routes from %s to %s", s.Origin, s.Destination)
+	}
+	return pairs, nil
+}
+
+// RoutePairs returns the resolved routes of the subscription or, when they
+// have not been resolved, every pair of the airports of its origin and
+// destination.
+func (s Setting) RoutePairs() []RoutePair {
+	if len(s.Routes) != 0 {
+		return s.Routes
+	}
+
+	pairs := []RoutePair{}
+	for _, origin := range SplitAirports(s.Origin) {
+		for _, destination := range SplitAirports(s.Destination) {
+			if origin != destination {
+				pairs = append(pairs, RoutePair{origin, destination})
+			}
+		}
+	}
+	return pairs
+}
+
+// ExpandRoutes resolves the routes of the subscriptions to groups of
+// airports. Subscriptions that can not be resolved are left out.
+func ExpandRoutes(settings []Setting, routes []wingo.Route) []Setting {
+	expanded := []Setting{}
+	for _, setting := range settings {
+		if setting.IsMultiAirport() {
+			pairs, err := setting.ResolveRoutes(routes)
+			if err != nil {
+				log.Printf("could not resolve routes of subscription %s: %v", setting.UID, err)
+				continue
+			}
+			setting.Routes = pairs
+		}
+		expanded = append(expanded, setting)
+	}
+	return expanded
+}
@@ -0,0 +1,153 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fabianMendez/wingo/pkg/date"
+	"github.com/fabianMendez/wingo/pkg/i18n"
+)
+
+// IsFlexible reports whether the subscription covers more than a single date.
+func (s Setting) IsFlexible() bool {
+	return s.DateFrom != "" || s.DateTo != "" || len(s.Weekdays) != 0
+}
+
+// DateRange returns the first and last dates covered by the subscription.
+func (s Setting) DateRange() (time.Time, time.Time, error) {
+	from := s.DateFrom
+	if from == "" {
+		from = s.Date
+	}
+	to := s.DateTo
+	if to == "" {
+		to = from
+	}
+
+	start, err := date.Parse(from)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := date.Parse(to)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errors.New("the end of the date range is before its start")
+	}
+
+	return start, end, nil
+}
+
+// MatchesDate reports whether flights on the given date are covered by the
+// subscription.
+func (s Setting) MatchesDate(d string) bool {
+	if !s.IsFlexible() {
+		return s.Date == d
+	}
+
+	t, err := date.Parse(d)
+	if err != nil {
+		return false
+	}
+
+	start, end, err := s.DateRange()
+	if err != nil || t.Before(start) || t.After(end) {
+		return false
+	}
+
+	if len(s.Weekdays) == 0 {
+		return true
+	}
+	for _, wd := range s.Weekdays {
+		weekday, err := date.ParseWeekday(wd)
+		if err == nil && weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// DateLabel describes the dates covered by the subscription.
+func (s Setting) DateLabel() string {
+	if !s.IsFlexible() {
+		return s.Date
+	}
+
+	start, end, err := s.DateRange()
+	if err != nil {
+		return s.Date
+	}
+
+	label := date.Format(start)
+	if !end.Equal(start) {
+		label += " - " + date.Format(end)
+	}
+	if len(s.Weekdays) != 0 {
+		label += " (" + strings.Join(s.Weekdays, ", ") + ")"
+	}
+	return label
+}
+
+// Validate checks the dates and airports of the subscription.
+func (s Setting) Validate() error {
+	if s.IsMultiAirport() && s.IsRoundTrip() {
+		return errors.New("groups of airports are not supported for round trips")
+	}
+
+	if s.FlightNumber != "" && s.IsRoundTrip() {
+		return errors.New("flight numbers are not supported for round trips")
+	}
+
+	if !i18n.IsSupported(s.Language) {
+		return fmt.Errorf("unsupported language: %s", s.Language)
+	}
+
+	if s.Cooldown != "" {
+		if cooldown, err := time.ParseDuration(s.Cooldown); err != nil || cooldown < 0 {
+			return fmt.Errorf("invalid cooldown: %s", s.Cooldown)
+		}
+	}
+
+	if !s.IsFlexible() {
+		_, err := date.Parse(s.Date)
+		return err
+	}
+
+	if s.IsRoundTrip() {
+		return errors.New("flexible dates are not supported for round trips")
+	}
+
+	if _, _, err := s.DateRange(); err != nil {
+		return err
+	}
+
+	for _, wd := range s.Weekdays {
+		if _, err := date.ParseWeekday(wd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifiesCheapest reports whether the subscription covers several dates or
+// airports, and is only notified about the cheapest flight among them.
+func (s Setting) NotifiesCheapest() bool {
+	return s.IsFlexible() || s.IsMultiAirport()
+}
+
+// SplitFlexible separates the subscriptions to a single flight date and route
+// from the ones notified about the cheapest flight.
+func SplitFlexible(settings []Setting) (fixed, flexible []Setting) {
+	fixed, flexible = []Setting{}, []Setting{}
+	for _, setting := range settings {
+		if setting.NotifiesCheapest() {
+			flexible = append(flexible, setting)
+		} else {
+			fixed = append(fixed, setting)
+		}
+	}
+	return fixed, flexible
+}
@@ -0,0 +1,252 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/fabianMendez/wingo/pkg/notify"
+	"github.com/fabianMendez/wingo/pkg/storage"
+)
+
+const historyDir = "history"
+
+// DefaultCooldown is the time a notification is not repeated for, unless
+// the subscription sets its cooldown.
+const DefaultCooldown = 24 * time.Hour
+
+// GetCooldown returns the window during which the same notification is not
+// sent twice. A zero cooldown disables the deduplication.
+func (s Setting) GetCooldown() time.Duration {
+	if s.Cooldown == "" {
+		return DefaultCooldown
+	}
+
+	cooldown, err := time.ParseDuration(s.Cooldown)
+	if err != nil || cooldown < 0 {
+		return DefaultCooldown
+	}
+	return cooldown
+}
+
+// History keeps when every subscription was sent each notification, in
+// history/<uid>.json, and the last price notified of each flight, in
+// history/prices/<uid>.json. The notifications are recorded in memory, and
+// written by Save.
+type History struct {
+	mutex       *sync.Mutex
+	backend     storage.Backend
+	sent        map[string]map[string]time.Time
+	dirty       map[string]time.Duration
+	prices      map[string]map[string]float64
+	dirtyPrices map[string]bool
+	now         func() time.Time
+}
+
+func NewHistory(backend storage.Backend) *History {
+	return &History{
+		mutex:       new(sync.Mutex),
+		backend:     backend,
+		sent:        map[string]map[string]time.Time{},
+		dirty:       map[string]time.Duration{},
+		prices:      map[string]map[string]float64{},
+		dirtyPrices: map[string]bool{},
+		now:         time.Now,
+	}
+}
+
+func historyPath(uid string) string {
+	return path.Join(historyDir, uid+".json")
+}
+
+func pricesPath(uid string) string {
+	return path.Join(historyDir, "prices", uid+".json")
+}
+
+// priceKey identifies the flight whose price is notified to the subscription.
+// The flexible, multi-airport and round trip subscriptions are notified
+// about their cheapest flight, whichever it is.
+func priceKey(sub Setting, origin, destination, date, flightNumber, cur string) string {
+	if sub.NotifiesCheapest() || sub.IsRoundTrip() {
+		return "cheapest/" + cur
+	}
+	return fmt.Sprintf("%s-%s/%s/%s/%s", origin, destination, date, flightNumber, cur)
+}
+
+func (h *History) load(uid string) (map[string]time.Time, error) {
+	if sent, found := h.sent[uid]; found {
+		return sent, nil
+	}
+
+	sent := map[string]time.Time{}
+	content, err := h.backend.Read(historyPath(uid))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &sent)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode notification history: %w", err)
+		}
+	}
+
+	h.sent[uid] = sent
+	return sent, nil
+}
+
+func (h *History) loadPrices(uid string) (map[string]float64, error) {
+	if prices, found := h.prices[uid]; found {
+		return prices, nil
+	}
+
+	prices := map[string]float64{}
+	content, err := h.backend.Read(pricesPath(uid))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &prices)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode notified prices: %w", err)
+		}
+	}
+
+	h.prices[uid] = prices
+	return prices, nil
+}
+
+// LastPrice returns the last price of the flight notified to the
+// subscription, if any.
+func (h *History) LastPrice(sub Setting, origin, destination, date, flightNumber, cur string) (float64, bool, error) {
+	if h == nil || sub.UID == "" {
+		return 0, false, nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	prices, err := h.loadPrices(sub.UID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	price, found := prices[priceKey(sub, origin, destination, date, flightNumber, cur)]
+	return price, found, nil
+}
+
+// RecordPrice keeps the price of a new flight or price change event sent to
+// the subscription, which the next changes are compared against.
+func (h *History) RecordPrice(sub Setting, event notify.Event) error {
+	if h == nil || sub.UID == "" {
+		return nil
+	}
+	if event.Type != notify.EventNewFlight && event.Type != notify.EventPriceChanged {
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	prices, err := h.loadPrices(sub.UID)
+	if err != nil {
+		return err
+	}
+
+	prices[priceKey(sub, event.Origin, event.Destination, event.Date, event.FlightNumber, event.Currency)] = event.NewPrice
+	h.dirtyPrices[sub.UID] = true
+	return nil
+}
+
+// Allow reports whether the notification identified by key can be sent to
+// the subscription, i.e. it was not recorded within its cooldown.
+func (h *History) Allow(sub Setting, key string) (bool, error) {
+	cooldown := sub.GetCooldown()
+	if h == nil || cooldown == 0 || sub.UID == "" {
+		return true, nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	sent, err := h.load(sub.UID)
+	if err != nil {
+		return true, err
+	}
+
+	last, found := sent[key]
+	return !found || h.now().Sub(last) >= cooldown, nil
+}
+
+// Record marks the notification identified by key as sent to the
+// subscription.
+func (h *History) Record(sub Setting, key string) error {
+	cooldown := sub.GetCooldown()
+	if h == nil || cooldown == 0 || sub.UID == "" {
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	sent, err := h.load(sub.UID)
+	if err != nil {
+		return err
+	}
+
+	sent[key] = h.now()
+	h.dirty[sub.UID] = cooldown
+	return nil
+}
+
+// Save writes the history of the subscriptions with new notifications,
+// leaving out the ones sent before their cooldown, and their notified prices.
+func (h *History) Save() error {
+	if h == nil {
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := h.now()
+	var firstErr error
+	for uid, cooldown := range h.dirty {
+		sent := h.sent[uid]
+		for k, t := range sent {
+			if now.Sub(t) >= cooldown {
+				delete(sent, k)
+			}
+		}
+
+		b, err := json.MarshalIndent(sent, "", "  ")
+		if err == nil {
+			err = h.backend.Write(historyPath(uid), b, "update notification history "+uid)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("could not save notification history: %w", err)
+			}
+			continue
+		}
+		delete(h.dirty, uid)
+	}
+
+	for uid := range h.dirtyPrices {
+		b, err := json.MarshalIndent(h.prices[uid], "", "  ")
+		if err == nil {
+			err = h.backend.Write(pricesPath(uid), b, "update notified prices "+uid)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("could not save notified prices: %w", err)
+			}
+			continue
+		}
+		delete(h.dirtyPrices, uid)
+	}
+
+	return firstErr
+}
@@ -3,13 +3,17 @@ package notifications
 import (
 	"encoding/json"
 	"fmt"
-	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sync"
 	"time"
 
-	"github.com/fabianMendez/wingo/pkg/date"
+	"github.com/fabianMendez/wingo"
+	"github.com/fabianMendez/wingo/pkg/currency"
+	"github.com/fabianMendez/wingo/pkg/i18n"
+	"github.com/fabianMendez/wingo/pkg/notify"
+	"github.com/fabianMendez/wingo/pkg/rules"
 	"github.com/fabianMendez/wingo/pkg/storage"
 	"github.com/google/uuid"
 )
@@ -24,13 +28,90 @@ func defaultIfEmpty(str, def string) string {
 }
 
 type Setting struct {
-	UID         string `json:"-"`
-	Origin      string `json:"origin"`
-	Destination string `json:"destination"`
-	Date        string `json:"date"`
-	Email       string `json:"email"`
-	PhoneNumber string `json:"phone_number"`
-	Confirmed   bool   `json:"confirmed"`
+	UID              string            `json:"-"`
+	Routes           []RoutePair       `json:"-"`
+	Origin           string            `json:"origin"`
+	Destination      string            `json:"destination"`
+	Date             string            `json:"date"`
+	DateFrom         string            `json:"date_from,omitempty"`
+	DateTo           string            `json:"date_to,omitempty"`
+	Weekdays         []string          `json:"weekdays,omitempty"`
+	ReturnDate       string            `json:"return_date,omitempty"`
+	FlightNumber     string            `json:"flight_number,omitempty"`
+	Passengers       *wingo.Passengers `json:"passengers,omitempty"`
+	Currency         string            `json:"currency,omitempty"`
+	Bundle           string            `json:"bundle,omitempty"`
+	BaggageAlerts    bool              `json:"baggage_alerts,omitempty"`
+	SeatsThreshold   int64             `json:"seats_threshold,omitempty"`
+	TargetPrice      float64           `json:"target_price,omitempty"`
+	MinChange        float64           `json:"min_change,omitempty"`
+	MinChangePercent float64           `json:"min_change_percent,omitempty"`
+	OnlyDrops        bool              `json:"only_drops,omitempty"`
+	Digest           bool              `json:"digest,omitempty"`
+	Channels         []string          `json:"channels,omitempty"`
+	Cooldown         string            `json:"cooldown,omitempty"`
+	Language         string            `json:"language,omitempty"`
+	Email            string            `json:"email"`
+	PhoneNumber      string            `json:"phone_number"`
+	TelegramChatID   string            `json:"telegram_chat_id,omitempty"`
+	TelegramToken    string            `json:"telegram_token,omitempty"`
+	WebhookURL       string            `json:"webhook_url,omitempty"`
+	Confirmed        bool              `json:"confirmed"`
+}
+
+func (s Setting) IsRoundTrip() bool {
+	return s.ReturnDate != ""
+}
+
+// GetPassengers returns the passenger mix whose total price is tracked, a
+// single adult unless the subscription sets one.
+func (s Setting) GetPassengers() wingo.Passengers {
+	if s.Passengers == nil {
+		return wingo.SingleAdult
+	}
+	return s.Passengers.OrDefault()
+}
+
+// GetCurrency returns the currency in which the prices are tracked.
+func (s Setting) GetCurrency() string {
+	return currency.Normalize(s.Currency)
+}
+
+// Rule returns the rule deciding which prices are notified.
+func (s Setting) Rule() rules.Rule {
+	return rules.Rule{
+		MaxPrice:         s.TargetPrice,
+		MinChange:        s.MinChange,
+		MinChangePercent: s.MinChangePercent,
+		OnlyDrops:        s.OnlyDrops,
+	}
+}
+
+// MatchesFlight reports whether the subscription is notified about the flight
+// with the given number, ignoring the carrier code.
+func (s Setting) MatchesFlight(flightNumber string) bool {
+	return s.FlightNumber == "" || wingo.CleanFlightNumber(s.FlightNumber) == wingo.CleanFlightNumber(flightNumber)
+}
+
+// GetBundle returns the fare family whose price is tracked.
+func (s Setting) GetBundle() string {
+	return wingo.NormalizeBundle(s.Bundle)
+}
+
+// GetLanguage returns the language of the notifications of the subscription.
+func (s Setting) GetLanguage() string {
+	return i18n.Normalize(s.Language)
+}
+
+// Recipient returns the addresses the subscription is notified at.
+func (s Setting) Recipient() notify.Recipient {
+	return notify.Recipient{
+		Email:          s.Email,
+		PhoneNumber:    s.PhoneNumber,
+		TelegramChatID: s.TelegramChatID,
+		WebhookURL:     s.WebhookURL,
+		Language:       s.GetLanguage(),
+	}
 }
 
 func BaseName(path string) string {
@@ -39,12 +120,13 @@ func BaseName(path string) string {
 	return base[:len(base)-len(ext)]
 }
 
-func LoadAllSettings() ([]Setting, error) {
-	fentries, err := os.ReadDir(dir)
+func settingPath(uid string) string {
+	return path.Join(dir, uid+".json")
+}
+
+func LoadAllSettings(backend storage.Backend) ([]Setting, error) {
+	names, err := backend.List(dir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
 		return nil, err
 	}
 
@@ -53,24 +135,24 @@ func LoadAllSettings() ([]Setting, error) {
 
 	wg := new(sync.WaitGroup)
 
-	for _, fentry := range fentries {
-		if fentry.IsDir() {
+	for _, name := range names {
+		if path.Ext(name) != ".json" {
 			continue
 		}
 
 		wg.Add(1)
-		go func(fentry fs.DirEntry) {
+		go func(name string) {
 			defer wg.Done()
 
-			fname := filepath.Join(dir, fentry.Name())
-			f, err := os.Open(fname)
+			fname := path.Join(dir, name)
+			content, err := backend.Read(fname)
 			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
 				return
 			}
 
-			defer f.Close()
 			var setting Setting
-			err = json.NewDecoder(f).Decode(&setting)
+			err = json.Unmarshal(content, &setting)
 			if err != nil {
 				fmt.Fprintln(os.Stderr, err)
 				return
@@ -80,26 +162,21 @@ func LoadAllSettings() ([]Setting, error) {
 			settingsMutex.Lock()
 			settings = append(settings, setting)
 			settingsMutex.Unlock()
-		}(fentry)
+		}(name)
 	}
 	wg.Wait()
 
 	return settings, nil
 }
 
-func SaveSetting(setting Setting) (string, error) {
+func SaveSetting(backend storage.Backend, setting Setting) (string, error) {
 	uid := uuid.New()
 	b, err := json.Marshal(setting)
 	if err != nil {
 		return "", err
 	}
 
-	fname := filepath.Join(dir, uid.String()+".json")
-	githubStorage, err := storage.NewGithubFromEnv()
-	if err != nil {
-		return "", err
-	}
-	err = githubStorage.Write(fname, b, "add subscription")
+	err = backend.Write(settingPath(uid.String()), b, "add subscription")
 	if err != nil {
 		return "", fmt.Errorf("could not save setting: %w", err)
 	}
@@ -107,18 +184,13 @@ func SaveSetting(setting Setting) (string, error) {
 	return uid.String(), nil
 }
 
-func UpdateSetting(uid string, setting Setting) error {
+func UpdateSetting(backend storage.Backend, uid string, setting Setting) error {
 	b, err := json.Marshal(setting)
 	if err != nil {
 		return err
 	}
 
-	fname := filepath.Join(dir, uid+".json")
-	githubStorage, err := storage.NewGithubFromEnv()
-	if err != nil {
-		return err
-	}
-	err = githubStorage.Write(fname, b, "update subscription")
+	err = backend.Write(settingPath(uid), b, "update subscription")
 	if err != nil {
 		return fmt.Errorf("could not save setting: %w", err)
 	}
@@ -126,13 +198,8 @@ func UpdateSetting(uid string, setting Setting) error {
 	return nil
 }
 
-func DeleteSetting(uid string) error {
-	fname := filepath.Join(dir, uid+".json")
-	githubStorage, err := storage.NewGithubFromEnv()
-	if err != nil {
-		return err
-	}
-	err = githubStorage.Delete(fname, "delete subscription")
+func DeleteSetting(backend storage.Backend, uid string) error {
+	err := backend.Delete(settingPath(uid), "delete subscription")
 	if err != nil {
 		return fmt.Errorf("could not delete setting: %w", err)
 	}
@@ -140,16 +207,10 @@ func DeleteSetting(uid string) error {
 	return nil
 }
 
-func GetSetting(uid string) (Setting, error) {
+func GetSetting(backend storage.Backend, uid string) (Setting, error) {
 	var setting Setting
-	fname := filepath.Join(dir, uid+".json")
-
-	githubStorage, err := storage.NewGithubFromEnv()
-	if err != nil {
-		return setting, err
-	}
 
-	content, err := githubStorage.Read(fname)
+	content, err := backend.Read(settingPath(uid))
 	if err != nil {
 		return setting, fmt.Errorf("could not read setting: %w", err)
 	}
@@ -158,6 +219,7 @@ func GetSetting(uid string) (Setting, error) {
 	if err != nil {
 		return setting, fmt.Errorf("could not decode setting: %w", err)
 	}
+	setting.UID = uid
 
 	return setting, nil
 }
@@ -172,31 +234,52 @@ func FilterConfirmed(settings []Setting) []Setting {
 	return filtered
 }
 
+func SplitRoundTrips(settings []Setting) (oneWay, roundTrip []Setting) {
+	oneWay, roundTrip = []Setting{}, []Setting{}
+	for _, setting := range settings {
+		if setting.IsRoundTrip() {
+			roundTrip = append(roundTrip, setting)
+		} else {
+			oneWay = append(oneWay, setting)
+		}
+	}
+	return
+}
+
+func GroupByCurrency(subs []Setting) map[string][]Setting {
+	grouped := map[string][]Setting{}
+	for _, sub := range subs {
+		grouped[sub.GetCurrency()] = append(grouped[sub.GetCurrency()], sub)
+	}
+	return grouped
+}
+
 func FilterBetweenDates(subscriptions []Setting, start, end time.Time) []Setting {
 	filtered := []Setting{}
 	for _, sub := range subscriptions {
-		d, err := date.Parse(sub.Date)
+		first, last, err := sub.DateRange()
 		if err != nil {
 			continue
 		}
-		if (start.Before(d) || start.Equal(d)) && end.After(d) {
+		if (start.Before(last) || start.Equal(last)) && end.After(first) {
 			filtered = append(filtered, sub)
 		}
 	}
 	return filtered
 }
 
+// GroupByRoute groups the subscriptions by origin and destination. A
+// subscription to groups of airports is added to each of its route pairs.
 func GroupByRoute(subs []Setting) map[string]map[string][]Setting {
 	grouped := map[string]map[string][]Setting{}
 
 	for _, sub := range subs {
-		if grouped[sub.Origin] == nil {
-			grouped[sub.Origin] = map[string][]Setting{}
-		}
-		if grouped[sub.Destination] == nil {
-			grouped[sub.Origin][sub.Destination] = []Setting{}
+		for _, pair := range sub.RoutePairs() {
+			if grouped[pair.Origin] == nil {
+				grouped[pair.Origin] = map[string][]Setting{}
+			}
+			grouped[pair.Origin][pair.Destination] = append(grouped[pair.Origin][pair.Destination], sub)
 		}
-		grouped[sub.Origin][sub.Destination] = append(grouped[sub.Origin][sub.Destination], sub)
 	}
 
 	return grouped
@@ -4,8 +4,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fabianMendez/wingo"
 	"github.com/fabianMendez/wingo/pkg/notifications"
+	"github.com/fabianMendez/wingo/pkg/notify"
+	"github.com/fabianMendez/wingo/pkg/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFilterBetweenDates(t *testing.T) {
@@ -48,6 +52,18 @@ func TestFilterBetweenDates(t *testing.T) {
 			start:    time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC),
 			end:      time.Date(2021, 12, 23, 0, 0, 0, 0, time.UTC),
 		},
+		{
+			name: "date range overlapping",
+			subscriptions: []notifications.Setting{
+				{DateFrom: "2021-12-01", DateTo: "2021-12-21"},
+				{DateFrom: "2021-12-01", DateTo: "2021-12-19"},
+			},
+			expected: []notifications.Setting{
+				{DateFrom: "2021-12-01", DateTo: "2021-12-21"},
+			},
+			start: time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC),
+			end:   time.Date(2021, 12, 23, 0, 0, 0, 0, time.UTC),
+		},
 		{
 			name: "some after range",
 			subscriptions: []notifications.Setting{
@@ -102,3 +118,218 @@ func TestBaseName(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveAndLoadSettings(t *testing.T) {
+	backend := storage.NewMemory()
+
+	uid, err := notifications.SaveSetting(backend, notifications.Setting{Origin: "BOG", Destination: "CTG", Date: "2022-01-01"})
+	require.NoError(t, err)
+
+	setting, err := notifications.GetSetting(backend, uid)
+	require.NoError(t, err)
+	assert.Equal(t, "BOG", setting.Origin)
+
+	setting.Confirmed = true
+	require.NoError(t, notifications.UpdateSetting(backend, uid, setting))
+
+	settings, err := notifications.LoadAllSettings(backend)
+	require.NoError(t, err)
+	assert.Equal(t, []notifications.Setting{setting}, settings)
+
+	require.NoError(t, notifications.DeleteSetting(backend, uid))
+	settings, err = notifications.LoadAllSettings(backend)
+	require.NoError(t, err)
+	assert.Empty(t, settings)
+}
+
+func TestMatchesDate(t *testing.T) {
+	fridaysInDecember := notifications.Setting{DateFrom: "2026-12-01", DateTo: "2026-12-31", Weekdays: []string{"friday"}}
+	assert.True(t, fridaysInDecember.MatchesDate("2026-12-04"))
+	assert.False(t, fridaysInDecember.MatchesDate("2026-12-05"))
+	assert.False(t, fridaysInDecember.MatchesDate("2027-01-01"))
+	assert.Equal(t, "2026-12-01 - 2026-12-31 (friday)", fridaysInDecember.DateLabel())
+
+	weekend := notifications.Setting{DateFrom: "2026-12-01", DateTo: "2026-12-31", Weekdays: []string{"sat", "domingo"}}
+	assert.True(t, weekend.MatchesDate("2026-12-05"))
+	assert.True(t, weekend.MatchesDate("2026-12-06"))
+	assert.False(t, weekend.MatchesDate("2026-12-07"))
+
+	fixed := notifications.Setting{Date: "2026-12-04"}
+	assert.True(t, fixed.MatchesDate("2026-12-04"))
+	assert.False(t, fixed.MatchesDate("2026-12-05"))
+	assert.Equal(t, "2026-12-04", fixed.DateLabel())
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, notifications.Setting{Date: "2026-12-04"}.Validate())
+	assert.NoError(t, notifications.Setting{DateFrom: "2026-12-01", DateTo: "2026-12-31"}.Validate())
+	assert.Error(t, notifications.Setting{DateFrom: "2026-12-31", DateTo: "2026-12-01"}.Validate())
+	assert.Error(t, notifications.Setting{Date: "2026-12-04", Weekdays: []string{"someday"}}.Validate())
+	assert.Error(t, notifications.Setting{DateFrom: "2026-12-01", ReturnDate: "2026-12-10"}.Validate())
+}
+
+func TestResolveRoutes(t *testing.T) {
+	routes := []wingo.Route{
+		{Code: "BOG", Country: "Colombia", Routes: []wingo.Route{{Code: "CTG"}, {Code: "SMR"}, {Code: "PTY"}}},
+		{Code: "MDE", Country: "Colombia", Routes: []wingo.Route{{Code: "CTG"}}},
+		{Code: "CTG", Country: "Colombia", Routes: []wingo.Route{{Code: "BOG"}, {Code: "MDE"}}},
+		{Code: "SMR", Country: "Colombia", Routes: []wingo.Route{{Code: "BOG"}}},
+		{Code: "PTY", Country: "Internacional", Routes: []wingo.Route{{Code: "BOG"}}},
+	}
+
+	pairs, err := notifications.Setting{Origin: "BOG,MDE", Destination: "CTG, smr"}.ResolveRoutes(routes)
+	require.NoError(t, err)
+	assert.Equal(t, []notifications.RoutePair{{"BOG", "CTG"}, {"BOG", "SMR"}, {"MDE", "CTG"}}, pairs)
+
+	pairs, err = notifications.Setting{Origin: "PTY", Destination: "colombia"}.ResolveRoutes(routes)
+	require.NoError(t, err)
+	assert.Equal(t, []notifications.RoutePair{{"PTY", "BOG"}}, pairs)
+
+	_, err = notifications.Setting{Origin: "BOG", Destination: "CTG,XXX"}.ResolveRoutes(routes)
+	assert.Error(t, err)
+
+	_, err = notifications.Setting{Origin: "SMR", Destination: "PTY"}.ResolveRoutes(routes)
+	assert.Error(t, err)
+}
+
+func TestGroupByRoute(t *testing.T) {
+	single := notifications.Setting{Origin: "BOG", Destination: "CTG"}
+	group := notifications.Setting{Origin: "BOG", Destination: "CARIBE"}
+
+	grouped := notifications.GroupByRoute([]notifications.Setting{single, group})
+	assert.Equal(t, []notifications.Setting{single, group}, grouped["BOG"]["CTG"])
+	assert.Equal(t, []notifications.Setting{group}, grouped["BOG"]["SMR"])
+	assert.True(t, group.IsMultiAirport())
+	assert.False(t, single.IsMultiAirport())
+}
+
+func TestIsMultiAirportCountry(t *testing.T) {
+	routes := []wingo.Route{
+		{Code: "BOG", Country: "Colombia", Routes: []wingo.Route{{Code: "PTY"}}},
+		{Code: "CTG", Country: "Colombia", Routes: []wingo.Route{{Code: "PTY"}}},
+		{Code: "PTY", Country: "Panamá", Routes: []wingo.Route{{Code: "BOG"}, {Code: "CTG"}}},
+	}
+	country := notifications.Setting{UID: "abc", Origin: "PTY", Destination: "Colombia"}
+	assert.True(t, country.IsMultiAirport())
+	assert.True(t, notifications.Setting{Origin: "BOG,CTG", Destination: "PTY"}.IsMultiAirport())
+
+	expanded := notifications.ExpandRoutes([]notifications.Setting{country}, routes)
+	require.Len(t, expanded, 1)
+	assert.Equal(t, []notifications.RoutePair{{"PTY", "BOG"}, {"PTY", "CTG"}}, expanded[0].Routes)
+}
+
+func TestMatchesFlight(t *testing.T) {
+	assert.True(t, notifications.Setting{}.MatchesFlight("7013"))
+	assert.True(t, notifications.Setting{FlightNumber: "P5-7013"}.MatchesFlight("7013"))
+	assert.True(t, notifications.Setting{FlightNumber: "p57013"}.MatchesFlight("P5-7013"))
+	assert.False(t, notifications.Setting{FlightNumber: "7013"}.MatchesFlight("7014"))
+	assert.Error(t, notifications.Setting{Date: "2026-12-04", ReturnDate: "2026-12-10", FlightNumber: "7013"}.Validate())
+}
+
+func TestHistory(t *testing.T) {
+	backend := storage.NewMemory()
+	history := notifications.NewHistory(backend)
+	sub := notifications.Setting{UID: "abc"}
+
+	allowed, err := history.Allow(sub, "price_changed/BOG-CTG/2026-12-01/7013/COP90000.00")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = history.Allow(sub, "price_changed/BOG-CTG/2026-12-01/7013/COP90000.00")
+	require.NoError(t, err)
+	assert.True(t, allowed, "notifications are only recorded once sent")
+
+	require.NoError(t, history.Record(sub, "price_changed/BOG-CTG/2026-12-01/7013/COP90000.00"))
+	allowed, err = history.Allow(sub, "price_changed/BOG-CTG/2026-12-01/7013/COP90000.00")
+	require.NoError(t, err)
+	assert.False(t, allowed, "the same notification is not repeated within the cooldown")
+
+	allowed, err = history.Allow(sub, "price_changed/BOG-CTG/2026-12-01/7013/COP100000.00")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = history.Allow(notifications.Setting{UID: "abc", Cooldown: "0s"}, "price_changed/BOG-CTG/2026-12-01/7013/COP90000.00")
+	require.NoError(t, err)
+	assert.True(t, allowed, "a zero cooldown disables the deduplication")
+
+	_, err = backend.Read("history/abc.json")
+	assert.ErrorIs(t, err, storage.ErrNotFound, "the history is written by Save")
+	require.NoError(t, history.Save())
+	allowed, err = notifications.NewHistory(backend).Allow(sub, "price_changed/BOG-CTG/2026-12-01/7013/COP90000.00")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// notifications sent before the cooldown are allowed again
+	err = backend.Write("history/def.json", []byte(`{"new_flight/BOG-CTG/2026-12-01/7013/COP90000.00": "2020-01-01T00:00:00Z"}`), "")
+	require.NoError(t, err)
+	allowed, err = notifications.NewHistory(backend).Allow(notifications.Setting{UID: "def"}, "new_flight/BOG-CTG/2026-12-01/7013/COP90000.00")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestHistoryPrices(t *testing.T) {
+	backend := storage.NewMemory()
+	history := notifications.NewHistory(backend)
+	sub := notifications.Setting{UID: "abc", Date: "2026-12-01", Cooldown: "0s"}
+	event := notify.Event{Type: notify.EventPriceChanged, Origin: "BOG", Destination: "CTG", Date: "2026-12-01", FlightNumber: "7013", Currency: "COP", NewPrice: 90000}
+
+	_, found, err := history.LastPrice(sub, "BOG", "CTG", "2026-12-01", "7013", "COP")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, history.RecordPrice(sub, event))
+	require.NoError(t, history.RecordPrice(sub, notify.Event{Type: notify.EventNotAvailable, Origin: "BOG", Destination: "CTG", Date: "2026-12-01", FlightNumber: "7013", Currency: "COP"}))
+	require.NoError(t, history.Save())
+
+	price, found, err := notifications.NewHistory(backend).LastPrice(sub, "BOG", "CTG", "2026-12-01", "7013", "COP")
+	require.NoError(t, err)
+	assert.True(t, found, "the prices are kept even without cooldown")
+	assert.Equal(t, 90000.0, price)
+
+	_, found, err = history.LastPrice(sub, "BOG", "CTG", "2026-12-01", "7014", "COP")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	flexible := notifications.Setting{UID: "def", DateFrom: "2026-12-01", DateTo: "2026-12-31"}
+	require.NoError(t, history.RecordPrice(flexible, event))
+	price, found, err = history.LastPrice(flexible, "BOG", "CTG", "2026-12-05", "7014", "COP")
+	require.NoError(t, err)
+	assert.True(t, found, "the cheapest flight is tracked whichever it is")
+	assert.Equal(t, 90000.0, price)
+}
+
+func TestGetCooldown(t *testing.T) {
+	assert.Equal(t, notifications.DefaultCooldown, notifications.Setting{}.GetCooldown())
+	assert.Equal(t, 6*time.Hour, notifications.Setting{Cooldown: "6h"}.GetCooldown())
+	assert.Equal(t, time.Duration(0), notifications.Setting{Cooldown: "0"}.GetCooldown())
+	assert.Error(t, notifications.Setting{Date: "2026-12-01", Cooldown: "tomorrow"}.Validate())
+}
+
+func TestLinkTelegramChat(t *testing.T) {
+	token, err := notifications.NewTelegramToken()
+	require.NoError(t, err)
+
+	uid := "7d444840-9dc0-11d1-b245-5ffdce74fad2"
+	parameter := notifications.TelegramStartParameter(uid, token)
+	assert.LessOrEqual(t, len(parameter), 64, "telegram limits the start parameters to 64 characters")
+
+	parsedUID, parsedToken, ok := notifications.ParseTelegramStartParameter(parameter)
+	require.True(t, ok)
+	assert.Equal(t, uid, parsedUID)
+	assert.Equal(t, token, parsedToken)
+
+	_, _, ok = notifications.ParseTelegramStartParameter(uid)
+	assert.False(t, ok, "the uid alone does not link a chat")
+	_, _, ok = notifications.ParseTelegramStartParameter("../settings_" + token)
+	assert.False(t, ok)
+
+	setting := notifications.Setting{TelegramToken: token}
+	assert.ErrorIs(t, setting.LinkTelegramChat("123", "other"), notifications.ErrInvalidTelegramToken)
+	assert.False(t, setting.Confirmed)
+
+	require.NoError(t, setting.LinkTelegramChat("123", token))
+	assert.Equal(t, "123", setting.TelegramChatID)
+	assert.True(t, setting.Confirmed)
+	assert.ErrorIs(t, setting.LinkTelegramChat("456", token), notifications.ErrInvalidTelegramToken, "the token is single-use")
+	assert.Equal(t, "123", setting.TelegramChatID)
+}
@@ -0,0 +1,59 @@
+package notifications
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidTelegramToken is returned when a chat is linked with a token
+// that is not the one of the subscription, or that was already used.
+var ErrInvalidTelegramToken = errors.New("invalid telegram token")
+
+// NewTelegramToken returns a random token to link a Telegram chat to a
+// subscription once.
+func NewTelegramToken() (string, error) {
+	b := make([]byte, 12)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("could not generate telegram token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// TelegramStartParameter returns the parameter of the
+// https://t.me/<bot>?start=<parameter> link of a subscription.
+func TelegramStartParameter(uid, token string) string {
+	return uid + "_" + token
+}
+
+// ParseTelegramStartParameter returns the subscription UID and the token of
+// a start parameter.
+func ParseTelegramStartParameter(parameter string) (uid, token string, ok bool) {
+	i := strings.LastIndex(parameter, "_")
+	if i <= 0 || i == len(parameter)-1 {
+		return "", "", false
+	}
+	if _, err := uuid.Parse(parameter[:i]); err != nil {
+		return "", "", false
+	}
+	return parameter[:i], parameter[i+1:], true
+}
+
+// LinkTelegramChat saves the chat in the subscription and confirms it when
+// token is the one of the subscription, which can not be used again.
+func (s *Setting) LinkTelegramChat(chatID, token string) error {
+	if s.TelegramToken == "" || subtle.ConstantTimeCompare([]byte(s.TelegramToken), []byte(token)) != 1 {
+		return ErrInvalidTelegramToken
+	}
+
+	s.TelegramChatID = chatID
+	s.TelegramToken = ""
+	s.Confirmed = true
+	return nil
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/fabianMendez/wingo/pkg/storage"
+)
+
+const deliveriesDir = "deliveries"
+
+// maxDeliveries is the amount of deliveries kept per subscription.
+const maxDeliveries = 50
+
+// Delivery is the result of sending an event to a webhook.
+type Delivery struct {
+	ID              string    `json:"id"`
+	URL             string    `json:"url"`
+	EventType       string    `json:"event_type"`
+	SubscriptionUID string    `json:"subscription_uid,omitempty"`
+	Time            time.Time `json:"time"`
+	Attempts        int       `json:"attempts"`
+	StatusCode      int       `json:"status_code,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+func (d Delivery) Succeeded() bool {
+	return d.Error == ""
+}
+
+// DeliveryRecorder keeps the results of the webhook deliveries.
+type DeliveryRecorder interface {
+	Record(delivery Delivery) error
+}
+
+// StorageRecorder keeps the last deliveries of every subscription in
+// deliveries/<uid>.json.
+type StorageRecorder struct {
+	mutex   *sync.Mutex
+	backend storage.Backend
+}
+
+func NewStorageRecorder(backend storage.Backend) *StorageRecorder {
+	return &StorageRecorder{mutex: new(sync.Mutex), backend: backend}
+}
+
+func deliveriesPath(uid string) string {
+	if uid == "" {
+		uid = "_"
+	}
+	return path.Join(deliveriesDir, uid+".json")
+}
+
+// Deliveries returns the recorded deliveries of a subscription, the most
+// recent last.
+func (sr *StorageRecorder) Deliveries(uid string) ([]Delivery, error) {
+	content, err := sr.backend.Read(deliveriesPath(uid))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var deliveries []Delivery
+	err = json.Unmarshal(content, &deliveries)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (sr *StorageRecorder) Record(delivery Delivery) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	deliveries, err := sr.Deliveries(delivery.SubscriptionUID)
+	if err != nil {
+		return err
+	}
+
+	deliveries = append(deliveries, delivery)
+	if len(deliveries) > maxDeliveries {
+		deliveries = deliveries[len(deliveries)-maxDeliveries:]
+	}
+
+	b, err := json.MarshalIndent(deliveries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return sr.backend.Write(deliveriesPath(delivery.SubscriptionUID), b, "record webhook delivery "+delivery.ID)
+}
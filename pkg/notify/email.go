@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fabianMendez/wingo/pkg/email"
+)
+
+// EmailChannel sends HTML emails with Sender.
+type EmailChannel struct {
+	Sender email.Sender
+}
+
+func (ec EmailChannel) HasAddress(recipient Recipient) bool {
+	return recipient.Email != ""
+}
+
+func (ec EmailChannel) Send(ctx context.Context, recipient Recipient, event Event) error {
+	if !ec.HasAddress(recipient) {
+		return ErrNoRecipient
+	}
+
+	template, data := emailTemplate(event)
+	return email.Send(ctx, ec.Sender, event.Language, event.Subject, event.Text(), template, data, strings.Split(recipient.Email, ",")...)
+}
+
+// emailTemplate returns the HTML template of event and the data to execute
+// it with.
+func emailTemplate(event Event) (string, interface{}) {
+	if event.Template != "" {
+		return event.Template, event.Data
+	}
+
+	if event.Type == EventDigest {
+		return email.TplDigest, map[string]interface{}{"Items": event.Events}
+	}
+	return email.TplPriceChange, event
+}
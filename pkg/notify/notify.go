@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	EventNewFlight           = "new_flight"
+	EventPriceChanged        = "price_changed"
+	EventNotAvailable        = "not_available"
+	EventLowSeats            = "low_seats"
+	EventBaggageFeesChanged  = "baggage_fees_changed"
+	EventDigest              = "digest"
+	EventConfirmSubscription = "confirm_subscription"
+)
+
+// ErrNoRecipient is returned by the channels that have no address for the
+// recipient, e.g. the WhatsApp channel for a recipient without phone number.
+var ErrNoRecipient = errors.New("no address for recipient")
+
+// Event is a notification about a flight or a subscription.
+type Event struct {
+	Type            string   `json:"type"`
+	SubscriptionUID string   `json:"subscription_uid,omitempty"`
+	Origin          string   `json:"origin,omitempty"`
+	Destination     string   `json:"destination,omitempty"`
+	Date            string   `json:"date,omitempty"`
+	FlightNumber    string   `json:"flight_number,omitempty"`
+	Currency        string   `json:"currency,omitempty"`
+	OldPrice        float64  `json:"old_price,omitempty"`
+	NewPrice        float64  `json:"new_price,omitempty"`
+	Seats           int64    `json:"seats,omitempty"`
+	Language        string   `json:"language,omitempty"`
+	Subject         string   `json:"subject"`
+	Message         string   `json:"message"`
+	Details         []string `json:"details,omitempty"`
+
+	Link                   string `json:"link,omitempty"`
+	LinkHistory            string `json:"link_history,omitempty"`
+	CancelSubscriptionLink string `json:"cancel_subscription_link,omitempty"`
+
+	// Events are the notifications summarized by a digest.
+	Events []Event `json:"events,omitempty"`
+
+	// Template and Data override the HTML email built for the event.
+	Template string      `json:"-"`
+	Data     interface{} `json:"-"`
+}
+
+// Text returns the plain text body of the event.
+func (e Event) Text() string {
+	if len(e.Events) == 0 {
+		return strings.Join(append([]string{e.Message}, e.Details...), "\n")
+	}
+
+	parts := make([]string, 0, len(e.Events))
+	for _, event := range e.Events {
+		part := event.Subject + "\n" + event.Text()
+		if event.Link != "" {
+			part += "\n" + event.Link
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Recipient holds the addresses of a subscriber in every channel, and the
+// language of its subscription.
+type Recipient struct {
+	Email          string `json:"email,omitempty"`
+	PhoneNumber    string `json:"phone_number,omitempty"`
+	TelegramChatID string `json:"telegram_chat_id,omitempty"`
+	WebhookURL     string `json:"webhook_url,omitempty"`
+	Language       string `json:"language,omitempty"`
+}
+
+// Channel delivers events to recipients.
+type Channel interface {
+	Send(ctx context.Context, recipient Recipient, event Event) error
+}
+
+// Addresser is implemented by the channels that can tell whether they have
+// an address for a recipient.
+type Addresser interface {
+	HasAddress(recipient Recipient) bool
+}
+
+// ChannelFunc adapts a function to the Channel interface.
+type ChannelFunc func(ctx context.Context, recipient Recipient, event Event) error
+
+func (fn ChannelFunc) Send(ctx context.Context, recipient Recipient, event Event) error {
+	return fn(ctx, recipient, event)
+}
+
+// Split returns a recipient for every address of r, in its language.
+func (r Recipient) Split() []Recipient {
+	recipients := []Recipient{}
+	if r.Email != "" {
+		recipients = append(recipients, Recipient{Email: r.Email, Language: r.Language})
+	}
+	if r.PhoneNumber != "" {
+		recipients = append(recipients, Recipient{PhoneNumber: r.PhoneNumber, Language: r.Language})
+	}
+	if r.TelegramChatID != "" {
+		recipients = append(recipients, Recipient{TelegramChatID: r.TelegramChatID, Language: r.Language})
+	}
+	if r.WebhookURL != "" {
+		recipients = append(recipients, Recipient{WebhookURL: r.WebhookURL, Language: r.Language})
+	}
+	return recipients
+}
+
+// DedupeKey identifies the notifications that are repeated: the same kind
+// of event about the same flight, price, seats and details. The details are
+// hashed, as they can be long.
+func (e Event) DedupeKey() string {
+	key := fmt.Sprintf("%s/%s-%s/%s/%s/%s%.2f/%d", e.Type, e.Origin, e.Destination, e.Date, e.FlightNumber, e.Currency, e.NewPrice, e.Seats)
+	if len(e.Details) != 0 {
+		sum := sha256.Sum256([]byte(strings.Join(e.Details, "\n")))
+		key += "/" + hex.EncodeToString(sum[:8])
+	}
+	return key
+}
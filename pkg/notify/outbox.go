@@ -0,0 +1,237 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fabianMendez/wingo/pkg/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+const outboxDir = "outbox"
+
+// DefaultMaxAttempts is the amount of deliveries tried for every outbox
+// entry before it is marked as failed.
+const DefaultMaxAttempts = 5
+
+// Dispatcher sends events to recipients through the given channels.
+type Dispatcher interface {
+	Send(ctx context.Context, channels []string, recipient Recipient, event Event) error
+}
+
+// OutboxEntry is a notification through a single channel.
+type OutboxEntry struct {
+	ID        string    `json:"id"`
+	Channel   string    `json:"channel"`
+	Recipient Recipient `json:"recipient"`
+	Event     Event     `json:"event"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Outbox keeps the notifications in a storage backend until they are
+// delivered. Pending entries are kept in outbox/pending/<id>.json and retried
+// by Retry, delivered ones are deleted and the ones that failed MaxAttempts
+// times are moved to outbox/failed/<id>.json.
+type Outbox struct {
+	MaxAttempts int
+
+	mutex    *sync.Mutex
+	backend  storage.Backend
+	registry *Registry
+	now      func() time.Time
+}
+
+func NewOutbox(backend storage.Backend, registry *Registry) *Outbox {
+	return &Outbox{
+		MaxAttempts: DefaultMaxAttempts,
+		mutex:       new(sync.Mutex),
+		backend:     backend,
+		registry:    registry,
+		now:         time.Now,
+	}
+}
+
+func (o *Outbox) entryPath(entry OutboxEntry) string {
+	return path.Join(outboxDir, entry.Status, entry.ID+".json")
+}
+
+func (o *Outbox) write(entry OutboxEntry) error {
+	b, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode outbox entry: %w", err)
+	}
+
+	return o.backend.Write(o.entryPath(entry), b, fmt.Sprintf("%s notification %s", entry.Status, entry.ID))
+}
+
+// Enqueue adds a pending entry for every channel, or for the default ones
+// when channels is empty. Channels without an address for recipient are
+// skipped.
+func (o *Outbox) Enqueue(channels []string, recipient Recipient, event Event) ([]OutboxEntry, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	now := o.now()
+	entries := []OutboxEntry{}
+	for _, channel := range o.registry.Resolve(channels) {
+		if ch, found := o.registry.Channel(channel); found {
+			if addresser, ok := ch.(Addresser); ok && !addresser.HasAddress(recipient) {
+				continue
+			}
+		}
+
+		entry := OutboxEntry{
+			ID:        uuid.NewString(),
+			Channel:   channel,
+			Recipient: recipient,
+			Event:     event,
+			Status:    StatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		err := o.write(entry)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// deliver sends a pending entry and updates its status.
+func (o *Outbox) deliver(ctx context.Context, entry OutboxEntry) (OutboxEntry, error) {
+	sendErr := o.registry.Send(ctx, []string{entry.Channel}, entry.Recipient, entry.Event)
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	pendingPath := o.entryPath(entry)
+	entry.Attempts++
+	entry.UpdatedAt = o.now()
+	switch {
+	case sendErr == nil:
+		entry.Status = StatusDelivered
+		entry.LastError = ""
+	case entry.Attempts >= o.MaxAttempts:
+		entry.Status = StatusFailed
+		entry.LastError = sendErr.Error()
+	default:
+		entry.LastError = sendErr.Error()
+	}
+
+	// the webhook deliveries are recorded by their channel, so nothing is
+	// kept of the delivered entries
+	if entry.Status != StatusDelivered {
+		err := o.write(entry)
+		if err != nil {
+			return entry, err
+		}
+	}
+
+	if entry.Status != StatusPending {
+		err := o.backend.Delete(pendingPath, "remove pending notification "+entry.ID)
+		if err != nil {
+			return entry, err
+		}
+	}
+
+	return entry, sendErr
+}
+
+// Send enqueues the event and tries to deliver it right away. Failed
+// deliveries are kept pending, so they are only logged; the returned error
+// is about the outbox itself.
+func (o *Outbox) Send(ctx context.Context, channels []string, recipient Recipient, event Event) error {
+	entries, err := o.Enqueue(channels, recipient, event)
+	if err != nil {
+		return fmt.Errorf("could not enqueue notification: %w", err)
+	}
+
+	for _, entry := range entries {
+		entry, err = o.deliver(ctx, entry)
+		if err != nil && entry.Status == StatusPending {
+			log.Printf("notification %s will be retried: %v", entry.ID, err)
+		} else if err != nil {
+			log.Printf("notification %s failed: %v", entry.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Pending returns the entries that are not delivered yet, the oldest first.
+func (o *Outbox) Pending() ([]OutboxEntry, error) {
+	dir := path.Join(outboxDir, StatusPending)
+	names, err := o.backend.List(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not list pending notifications: %w", err)
+	}
+
+	entries := []OutboxEntry{}
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+
+		content, err := o.backend.Read(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		var entry OutboxEntry
+		err = json.Unmarshal(content, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode notification %s: %w", name, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// Retry tries to deliver the pending entries again and returns how many
+// were delivered.
+func (o *Outbox) Retry(ctx context.Context) (int, error) {
+	entries, err := o.Pending()
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+
+		entry, err = o.deliver(ctx, entry)
+		if err == nil {
+			delivered++
+		} else if entry.Status == StatusFailed {
+			log.Printf("notification %s failed after %d attempts: %v", entry.ID, entry.Attempts, err)
+		}
+	}
+
+	return delivered, nil
+}
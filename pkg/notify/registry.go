@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fabianMendez/wingo/pkg/email"
+	"github.com/fabianMendez/wingo/pkg/storage"
+	"github.com/fabianMendez/wingo/pkg/telegram"
+	"github.com/fabianMendez/wingo/pkg/whatsapp"
+)
+
+const (
+	ChannelEmail    = "email"
+	ChannelMailgun  = "mailgun"
+	ChannelSMTP     = "smtp"
+	ChannelWhatsApp = "whatsapp"
+	ChannelTelegram = "telegram"
+	ChannelWebhook  = "webhook"
+	ChannelStdout   = "stdout"
+	ChannelFile     = "file"
+)
+
+// DefaultChannels are used for the subscriptions that do not choose any.
+var DefaultChannels = []string{ChannelEmail, ChannelWhatsApp, ChannelWebhook}
+
+// Registry holds the available channels by name.
+type Registry struct {
+	channels map[string]Channel
+	defaults []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		channels: map[string]Channel{},
+		defaults: DefaultChannels,
+	}
+}
+
+func (r *Registry) Register(name string, channel Channel) {
+	r.channels[strings.ToLower(name)] = channel
+}
+
+func (r *Registry) Channel(name string) (Channel, bool) {
+	channel, found := r.channels[strings.ToLower(strings.TrimSpace(name))]
+	return channel, found
+}
+
+// Names returns the names of the registered channels.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.channels))
+	for name := range r.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetDefaults sets the channels used when none is chosen.
+func (r *Registry) SetDefaults(names []string) {
+	r.defaults = names
+}
+
+// Validate checks that every channel in names is registered.
+func (r *Registry) Validate(names []string) error {
+	for _, name := range names {
+		if _, found := r.Channel(name); !found {
+			return fmt.Errorf("unknown notification channel: %s", name)
+		}
+	}
+	return nil
+}
+
+// Resolve returns names, or the default channels when it is empty.
+func (r *Registry) Resolve(names []string) []string {
+	if len(names) == 0 {
+		return r.defaults
+	}
+	return names
+}
+
+// Send sends event to recipient through the given channels, or the default
+// ones when names is empty. Every channel is tried even if another one
+// fails; channels without an address for the recipient are skipped.
+func (r *Registry) Send(ctx context.Context, names []string, recipient Recipient, event Event) error {
+	names = r.Resolve(names)
+
+	var errs []string
+	for _, name := range names {
+		channel, found := r.Channel(name)
+		if !found {
+			errs = append(errs, fmt.Sprintf("unknown notification channel: %s", name))
+			continue
+		}
+
+		err := channel.Send(ctx, recipient, event)
+		if err != nil && !errors.Is(err, ErrNoRecipient) {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("could not send notification: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// NewRegistryFromEnv registers the channels configured in the environment.
+// Email (through the provider chosen by EMAIL_PROVIDER), Mailgun, the
+// webhook and stdout are always available; WhatsApp, SMTP, Telegram and the
+// file channels are registered when they are configured. NOTIFY_CHANNELS is
+// a comma separated list of the default channels, otherwise the registered
+// ones among Telegram and DefaultChannels. Webhook deliveries are recorded
+// in backend, unless it is nil.
+func NewRegistryFromEnv(backend storage.Backend) (*Registry, error) {
+	r := NewRegistry()
+
+	sender, err := email.NewSenderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	r.Register(ChannelEmail, EmailChannel{Sender: sender})
+	r.Register(ChannelMailgun, EmailChannel{Sender: email.MailgunSender{}})
+	r.Register(ChannelStdout, NewWriterChannel(os.Stdout))
+
+	wa, err := whatsapp.NewClientFromEnv()
+	if err == nil {
+		r.Register(ChannelWhatsApp, WhatsAppChannel{Client: wa})
+	} else if !errors.Is(err, whatsapp.ErrNotConfigured) {
+		return nil, err
+	}
+
+	if os.Getenv("SMTP_HOST") != "" {
+		smtp, err := email.NewSMTPSenderFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		r.Register(ChannelSMTP, EmailChannel{Sender: smtp})
+	}
+
+	if os.Getenv("TELEGRAM_BOT_TOKEN") != "" {
+		r.Register(ChannelTelegram, TelegramChannel{Client: telegram.NewClientFromEnv()})
+	}
+
+	webhook := NewWebhookChannel(os.Getenv("NOTIFY_WEBHOOK_URL"))
+	webhook.Secret = os.Getenv("NOTIFY_WEBHOOK_SECRET")
+	webhook.SigningKey = os.Getenv("NOTIFY_WEBHOOK_SIGNING_KEY")
+	if backend != nil {
+		webhook.Recorder = NewStorageRecorder(backend)
+	}
+	r.Register(ChannelWebhook, webhook)
+
+	if filename := os.Getenv("NOTIFY_FILE"); filename != "" {
+		r.Register(ChannelFile, NewFileChannel(filename))
+	}
+
+	defaults := []string{}
+	for _, name := range append([]string{ChannelTelegram}, DefaultChannels...) {
+		if _, found := r.Channel(name); found {
+			defaults = append(defaults, name)
+		}
+	}
+	r.SetDefaults(defaults)
+
+	if defaults := os.Getenv("NOTIFY_CHANNELS"); defaults != "" {
+		names := []string{}
+		for _, name := range strings.Split(defaults, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+
+		err = r.Validate(names)
+		if err != nil {
+			return nil, err
+		}
+		r.SetDefaults(names)
+	}
+
+	return r, nil
+}
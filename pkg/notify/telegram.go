@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fabianMendez/wingo/pkg/i18n"
+	"github.com/fabianMendez/wingo/pkg/telegram"
+)
+
+// TelegramChannel sends MarkdownV2 messages through a Telegram bot.
+type TelegramChannel struct {
+	Client *telegram.Client
+}
+
+func (tc TelegramChannel) HasAddress(recipient Recipient) bool {
+	return recipient.TelegramChatID != ""
+}
+
+func (tc TelegramChannel) Send(ctx context.Context, recipient Recipient, event Event) error {
+	if !tc.HasAddress(recipient) {
+		return ErrNoRecipient
+	}
+
+	return tc.Client.SendMessage(ctx, recipient.TelegramChatID, FormatMarkdownV2(event), telegram.ParseModeMarkdownV2)
+}
+
+// FormatMarkdownV2 formats event for the Telegram MarkdownV2 parse mode.
+func FormatMarkdownV2(event Event) string {
+	if len(event.Events) != 0 {
+		parts := []string{"*" + telegram.EscapeMarkdownV2(event.Subject) + "*"}
+		for _, e := range event.Events {
+			parts = append(parts, FormatMarkdownV2(e))
+		}
+		return strings.Join(parts, "\n\n")
+	}
+
+	lines := []string{"*" + telegram.EscapeMarkdownV2(event.Subject) + "*"}
+	if event.Message != "" {
+		lines = append(lines, telegram.EscapeMarkdownV2(event.Message))
+	}
+	for _, detail := range event.Details {
+		lines = append(lines, "• "+telegram.EscapeMarkdownV2(detail))
+	}
+
+	links := []string{}
+	if event.Link != "" {
+		links = append(links, telegram.LinkMarkdownV2(i18n.T(event.Language, "link.flight"), event.Link))
+	}
+	if event.LinkHistory != "" {
+		links = append(links, telegram.LinkMarkdownV2(i18n.T(event.Language, "link.history"), event.LinkHistory))
+	}
+	if event.CancelSubscriptionLink != "" {
+		links = append(links, telegram.LinkMarkdownV2(i18n.T(event.Language, "link.cancel"), event.CancelSubscriptionLink))
+	}
+	if len(links) != 0 {
+		lines = append(lines, strings.Join(links, " \\| "))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderSignature = "X-Wingo-Signature"
+	HeaderEvent     = "X-Wingo-Event"
+	HeaderDelivery  = "X-Wingo-Delivery"
+)
+
+// WebhookChannel posts the events as JSON to the webhook URL of the
+// recipient, or to URL when it has none. The body is signed with the
+// secret of the subscription, derived from SigningKey, or with Secret when
+// posting to URL. The requests that fail with
+// a 5xx status or a network error are retried with exponential backoff, and
+// the URLs of the recipients are checked with ValidateURL.
+type WebhookChannel struct {
+	URL         string
+	Secret      string
+	SigningKey  string
+	ValidateURL func(ctx context.Context, u string) error
+	HTTPClient  *http.Client
+	MaxAttempts int
+	Backoff     time.Duration
+	Recorder    DeliveryRecorder
+}
+
+func NewWebhookChannel(u string) *WebhookChannel {
+	return &WebhookChannel{
+		URL:         u,
+		ValidateURL: ValidateWebhookURL,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+	}
+}
+
+// WebhookPayload is the body posted to the webhooks.
+type WebhookPayload struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Event
+}
+
+// Sign returns the value of the signature header of body: "sha256=" and the
+// hex encoded HMAC-SHA256 of body with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SubscriptionSecret returns the secret the webhooks of a subscription are
+// signed with. It is derived from key, so it does not have to be stored.
+func SubscriptionSecret(key, uid string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(uid))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the signature header of body.
+func VerifySignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func (wc *WebhookChannel) HasAddress(recipient Recipient) bool {
+	return recipient.WebhookURL != "" || wc.URL != ""
+}
+
+func (wc *WebhookChannel) Send(ctx context.Context, recipient Recipient, event Event) error {
+	u, secret := recipient.WebhookURL, ""
+	if u == "" {
+		u, secret = wc.URL, wc.Secret
+	} else {
+		if wc.ValidateURL != nil {
+			err := wc.ValidateURL(ctx, u)
+			if err != nil {
+				return err
+			}
+		}
+		if wc.SigningKey != "" && event.SubscriptionUID != "" {
+			secret = SubscriptionSecret(wc.SigningKey, event.SubscriptionUID)
+		}
+	}
+	if u == "" {
+		return ErrNoRecipient
+	}
+
+	payload := WebhookPayload{ID: uuid.NewString(), Timestamp: time.Now().UTC(), Event: event}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not encode event: %w", err)
+	}
+
+	delivery := Delivery{
+		ID:              payload.ID,
+		URL:             u,
+		EventType:       event.Type,
+		SubscriptionUID: event.SubscriptionUID,
+		Time:            payload.Timestamp,
+	}
+
+	backoff := wc.Backoff
+	for {
+		delivery.Attempts++
+		var retry bool
+		delivery.StatusCode, retry, err = wc.post(ctx, u, secret, payload, body)
+		if err == nil || !retry || delivery.Attempts >= wc.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+			continue
+		}
+		break
+	}
+
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if wc.Recorder != nil {
+		recordErr := wc.Recorder.Record(delivery)
+		if recordErr != nil && err == nil {
+			err = fmt.Errorf("could not record delivery: %w", recordErr)
+		}
+	}
+
+	return err
+}
+
+// post sends a single request and returns whether it can be retried.
+func (wc *WebhookChannel) post(ctx context.Context, u, secret string, payload WebhookPayload, body []byte) (int, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, payload.Type)
+	req.Header.Set(HeaderDelivery, payload.ID)
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, body))
+	}
+
+	resp, err := wc.HTTPClient.Do(req)
+	if err != nil {
+		return 0, ctx.Err() == nil, fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, resp.StatusCode >= 500, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, false, nil
+}
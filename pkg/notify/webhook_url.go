@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// privateNetworks are the ranges webhooks of the subscriptions can not be
+// sent to.
+var privateNetworks = func() []*net.IPNet {
+	cidrs := []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7", "fe80::/10",
+	}
+
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateWebhookURL checks that a webhook URL given by a subscriber uses
+// https and that its host only resolves to public addresses.
+func ValidateWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("invalid webhook url: %s", raw)
+	}
+
+	ips := []net.IP{net.ParseIP(u.Hostname())}
+	if ips[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+		if err != nil {
+			return fmt.Errorf("could not resolve webhook host: %w", err)
+		}
+
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("webhook url %s is not a public address", raw)
+		}
+	}
+	return nil
+}
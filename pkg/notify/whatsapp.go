@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/fabianMendez/wingo/pkg/whatsapp"
+)
+
+// WhatsAppChannel sends text messages with a WhatsApp client.
+type WhatsAppChannel struct {
+	Client whatsapp.Client
+}
+
+func (wc WhatsAppChannel) HasAddress(recipient Recipient) bool {
+	return recipient.PhoneNumber != ""
+}
+
+func (wc WhatsAppChannel) Send(ctx context.Context, recipient Recipient, event Event) error {
+	if !wc.HasAddress(recipient) {
+		return ErrNoRecipient
+	}
+
+	if client, ok := wc.Client.(whatsapp.LocalizedClient); ok {
+		return client.SendLocalizedMessage(ctx, recipient.PhoneNumber, recipient.Language, event.Subject, event.Text())
+	}
+	return wc.Client.SendMessage(ctx, recipient.PhoneNumber, event.Subject, event.Text())
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WriterChannel writes every event as a JSON line. It is meant for dry runs
+// and debugging.
+type WriterChannel struct {
+	mutex *sync.Mutex
+	w     io.Writer
+	now   func() time.Time
+}
+
+func NewWriterChannel(w io.Writer) *WriterChannel {
+	return &WriterChannel{mutex: new(sync.Mutex), w: w, now: time.Now}
+}
+
+type writerEntry struct {
+	Time      time.Time `json:"time"`
+	Recipient Recipient `json:"recipient"`
+	Event     Event     `json:"event"`
+}
+
+func (wc *WriterChannel) Send(ctx context.Context, recipient Recipient, event Event) error {
+	b, err := json.Marshal(writerEntry{Time: wc.now(), Recipient: recipient, Event: event})
+	if err != nil {
+		return fmt.Errorf("could not encode event: %w", err)
+	}
+
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+
+	_, err = wc.w.Write(append(b, '\n'))
+	return err
+}
+
+// FileChannel appends the events as JSON lines to a file.
+type FileChannel struct {
+	Filename string
+	mutex    *sync.Mutex
+}
+
+func NewFileChannel(filename string) *FileChannel {
+	return &FileChannel{Filename: filename, mutex: new(sync.Mutex)}
+}
+
+func (fc *FileChannel) Send(ctx context.Context, recipient Recipient, event Event) error {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	f, err := os.OpenFile(fc.Filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open file: %w", err)
+	}
+	defer f.Close()
+
+	return NewWriterChannel(f).Send(ctx, recipient, event)
+}
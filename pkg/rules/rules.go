@@ -0,0 +1,54 @@
+package rules
+
+import "math"
+
+// Rule decides which prices are worth a notification. The zero Rule notifies
+// every new flight and every price change.
+type Rule struct {
+	// MaxPrice only allows prices lower or equal to it.
+	MaxPrice float64
+	// MinChange is the minimum absolute difference between two prices.
+	MinChange float64
+	// MinChangePercent is the minimum difference between two prices, as a
+	// percentage of the old one.
+	MinChangePercent float64
+	// OnlyDrops ignores price increases.
+	OnlyDrops bool
+}
+
+func (r Rule) allowsPrice(price float64) bool {
+	return r.MaxPrice <= 0 || price <= r.MaxPrice
+}
+
+// ShouldNotifyNew reports whether a flight that was not available before
+// should be notified at the given price.
+func (r Rule) ShouldNotifyNew(price float64) bool {
+	return r.allowsPrice(price)
+}
+
+// ShouldNotifyChange reports whether a change from oldPrice to newPrice should
+// be notified.
+func (r Rule) ShouldNotifyChange(oldPrice, newPrice float64) bool {
+	if oldPrice == newPrice {
+		return false
+	}
+
+	if r.OnlyDrops && newPrice > oldPrice {
+		return false
+	}
+
+	if !r.allowsPrice(newPrice) {
+		return false
+	}
+
+	change := math.Abs(newPrice - oldPrice)
+	if change < r.MinChange {
+		return false
+	}
+
+	if r.MinChangePercent > 0 && oldPrice > 0 && change/oldPrice*100 < r.MinChangePercent {
+		return false
+	}
+
+	return true
+}
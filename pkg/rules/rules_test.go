@@ -0,0 +1,40 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/fabianMendez/wingo/pkg/rules"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldNotifyChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     rules.Rule
+		old, new float64
+		expected bool
+	}{
+		{name: "any change", old: 100, new: 101, expected: true},
+		{name: "same price", old: 100, new: 100, expected: false},
+		{name: "only drops increase", rule: rules.Rule{OnlyDrops: true}, old: 100, new: 120, expected: false},
+		{name: "only drops decrease", rule: rules.Rule{OnlyDrops: true}, old: 120, new: 100, expected: true},
+		{name: "above ceiling", rule: rules.Rule{MaxPrice: 90}, old: 120, new: 100, expected: false},
+		{name: "below ceiling", rule: rules.Rule{MaxPrice: 100}, old: 120, new: 100, expected: true},
+		{name: "small change", rule: rules.Rule{MinChange: 10}, old: 100, new: 95, expected: false},
+		{name: "big change", rule: rules.Rule{MinChange: 10}, old: 100, new: 90, expected: true},
+		{name: "small percentage", rule: rules.Rule{MinChangePercent: 5}, old: 1000, new: 1040, expected: false},
+		{name: "big percentage", rule: rules.Rule{MinChangePercent: 5}, old: 1000, new: 950, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.rule.ShouldNotifyChange(tt.old, tt.new))
+		})
+	}
+}
+
+func TestShouldNotifyNew(t *testing.T) {
+	assert.True(t, rules.Rule{}.ShouldNotifyNew(500))
+	assert.True(t, rules.Rule{MaxPrice: 500, MinChange: 100}.ShouldNotifyNew(500))
+	assert.False(t, rules.Rule{MaxPrice: 400}.ShouldNotifyNew(500))
+}
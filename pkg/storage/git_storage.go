@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitStorage is a LocalStorage in a git working tree. Its history is the
+// one of the repository, so every committed revision of a file is available.
+type GitStorage struct {
+	LocalStorage
+}
+
+func NewGit(root string) GitStorage {
+	return GitStorage{LocalStorage: NewLocal(root)}
+}
+
+func (gs GitStorage) git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", gs.Root}, args...)...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not run git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// History returns the committed revisions of path, the oldest first, dated
+// by their commit.
+func (gs GitStorage) History(path string, since time.Time) ([]Revision, error) {
+	// deletions have no content to return
+	out, err := gs.git("log", "--reverse", "--diff-filter=ACMRT", "--format=%H %ct", "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []Revision{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse commit date of %s: %w", fields[0], err)
+		}
+		date := time.Unix(timestamp, 0).UTC()
+		if date.Before(since) {
+			continue
+		}
+
+		content, err := gs.git("show", fields[0]+":./"+path)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, Revision{Date: date, Content: content})
+	}
+
+	return revisions, nil
+}
+
+// Paths returns the files under dir committed at some point, including the
+// ones deleted since.
+func (gs GitStorage) Paths(dir string) ([]string, error) {
+	out, err := gs.git("log", "--relative", "--name-only", "--diff-filter=ACMRT", "--format=", "--", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	paths := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		paths = append(paths, line)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -4,15 +4,22 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/fabianMendez/bits/syncbits"
 )
 
+const maxWorkers = 10
+
 type GithubStorage struct {
 	Token string
 	Owner string
@@ -63,7 +70,13 @@ func (ss GithubStorage) request(method, u string, body io.Reader, headers map[st
 		return nil, fmt.Errorf("could not send request: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("request failed: %s: %w", resp.Status, ErrNotFound)
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		resp.Body.Close()
 		return nil, fmt.Errorf("request failed: %s", resp.Status)
 	}
 
@@ -235,3 +248,67 @@ func (ss GithubStorage) Commits(path string, since time.Time) (map[string]string
 	}
 	return results, nil
 }
+
+func (ss GithubStorage) List(dir string) ([]string, error) {
+	var response []fileContentsResponse
+
+	err := ss.requestJSON(http.MethodGet, ss.url(dir), nil, &response)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	names := make([]string, 0, len(response))
+	for _, entry := range response {
+		names = append(names, entry.Name)
+	}
+	return names, nil
+}
+
+func (ss GithubStorage) History(path string, since time.Time) ([]Revision, error) {
+	hashes, err := ss.Commits(path, since)
+	if err != nil {
+		return nil, fmt.Errorf("could not get commits: %w", err)
+	}
+
+	type task struct {
+		sha  string
+		date time.Time
+	}
+	ch := make(chan task, maxWorkers)
+	mutex := &sync.Mutex{}
+	revisions := []Revision{}
+
+	wg := syncbits.Workgroup(func() {
+		for t := range ch {
+			content, err := ss.ReadRef(path, t.sha)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "could not read ref: ", err)
+				continue
+			}
+
+			mutex.Lock()
+			revisions = append(revisions, Revision{Date: t.date, Content: content})
+			mutex.Unlock()
+		}
+	}, maxWorkers)
+
+	for datestr, hash := range hashes {
+		d, err := time.Parse(time.RFC3339, datestr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "could not parse commit date: ", err)
+			continue
+		}
+		ch <- task{hash, d}
+	}
+	close(ch)
+	wg.Wait()
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Date.Before(revisions[j].Date)
+	})
+
+	return revisions, nil
+}
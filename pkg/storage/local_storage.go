@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LocalStorage keeps files in a directory of the local filesystem. It does
+// not track history, so only the current revision of a file is available.
+type LocalStorage struct {
+	Root string
+}
+
+func NewLocal(root string) LocalStorage {
+	return LocalStorage{Root: root}
+}
+
+func NewLocalFromEnv() LocalStorage {
+	root := os.Getenv("STORAGE_DIR")
+	if root == "" {
+		root = "."
+	}
+	return NewLocal(root)
+}
+
+func (ls LocalStorage) path(path string) string {
+	return filepath.Join(ls.Root, filepath.FromSlash(path))
+}
+
+func (ls LocalStorage) Read(path string) ([]byte, error) {
+	b, err := os.ReadFile(ls.path(path))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	return b, err
+}
+
+func (ls LocalStorage) Write(path string, b []byte, message string) error {
+	fname := ls.path(path)
+	err := os.MkdirAll(filepath.Dir(fname), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, b, os.ModePerm)
+}
+
+func (ls LocalStorage) Delete(path string, message string) error {
+	err := os.Remove(ls.path(path))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	return err
+}
+
+func (ls LocalStorage) List(dir string) ([]string, error) {
+	direntries, err := os.ReadDir(ls.path(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	names := make([]string, 0, len(direntries))
+	for _, dentry := range direntries {
+		names = append(names, dentry.Name())
+	}
+	return names, nil
+}
+
+func (ls LocalStorage) History(path string, since time.Time) ([]Revision, error) {
+	fname := ls.path(path)
+	info, err := os.Stat(fname)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if info.ModTime().Before(since) {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Revision{{Date: info.ModTime(), Content: b}}, nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStorage keeps every revision of its files in memory. It is meant for
+// tests and dry runs.
+type MemoryStorage struct {
+	mutex *sync.Mutex
+	files map[string][]Revision
+	now   func() time.Time
+}
+
+func NewMemory() *MemoryStorage {
+	return &MemoryStorage{
+		mutex: new(sync.Mutex),
+		files: map[string][]Revision{},
+		now:   time.Now,
+	}
+}
+
+func (ms *MemoryStorage) Read(path string) ([]byte, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	revisions := ms.files[path]
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+
+	content := revisions[len(revisions)-1].Content
+	return append([]byte(nil), content...), nil
+}
+
+func (ms *MemoryStorage) Write(path string, b []byte, message string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.files[path] = append(ms.files[path], Revision{
+		Date:    ms.now(),
+		Content: append([]byte(nil), b...),
+	})
+	return nil
+}
+
+func (ms *MemoryStorage) Delete(path string, message string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if _, found := ms.files[path]; !found {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	delete(ms.files, path)
+	return nil
+}
+
+func (ms *MemoryStorage) List(dir string) ([]string, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	seen := map[string]bool{}
+	names := []string{}
+
+	for path := range ms.files {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(path, prefix)
+		if i := strings.Index(name, "/"); i != -1 {
+			name = name[:i]
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (ms *MemoryStorage) History(path string, since time.Time) ([]Revision, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	revisions := []Revision{}
+	for _, revision := range ms.files[path] {
+		if !revision.Date.Before(since) {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
@@ -0,0 +1,589 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fabianMendez/wingo"
+	_ "modernc.org/sqlite"
+)
+
+const KindSQLite = "sqlite"
+
+const (
+	passengerAdult  = "adult"
+	passengerChild  = "child"
+	passengerInfant = "infant"
+)
+
+// passengerTypes are the single passengers the prices are kept for.
+var passengerTypes = map[string]wingo.Passengers{
+	passengerAdult:  {Adults: 1},
+	passengerChild:  {Children: 1},
+	passengerInfant: {Infants: 1},
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS files (
+	path       TEXT PRIMARY KEY,
+	content    BLOB NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+	uid          TEXT PRIMARY KEY,
+	origin       TEXT NOT NULL,
+	destination  TEXT NOT NULL,
+	date         TEXT NOT NULL,
+	email        TEXT NOT NULL,
+	phone_number TEXT NOT NULL,
+	confirmed    INTEGER NOT NULL,
+	data         TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS flight_snapshots (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	origin        TEXT NOT NULL,
+	destination   TEXT NOT NULL,
+	date          TEXT NOT NULL,
+	flight_number TEXT NOT NULL,
+	observed_at   TIMESTAMP NOT NULL,
+	data          TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS flight_snapshots_flight
+	ON flight_snapshots (origin, destination, date, flight_number, observed_at);
+
+CREATE TABLE IF NOT EXISTS flight_prices (
+	snapshot_id    INTEGER NOT NULL REFERENCES flight_snapshots (id),
+	origin         TEXT NOT NULL,
+	destination    TEXT NOT NULL,
+	date           TEXT NOT NULL,
+	flight_number  TEXT NOT NULL,
+	observed_at    TIMESTAMP NOT NULL,
+	bundle         TEXT NOT NULL,
+	passenger_type TEXT NOT NULL,
+	price          REAL NOT NULL,
+	admin_fares    REAL NOT NULL,
+	PRIMARY KEY (snapshot_id, bundle, passenger_type)
+);
+
+CREATE INDEX IF NOT EXISTS flight_prices_flight
+	ON flight_prices (origin, destination, date, flight_number, bundle, observed_at);
+
+CREATE TABLE IF NOT EXISTS flight_seats (
+	snapshot_id     INTEGER NOT NULL REFERENCES flight_snapshots (id),
+	fare_index      INTEGER NOT NULL,
+	passenger_type  TEXT NOT NULL,
+	fare_id         INTEGER NOT NULL,
+	fare_amount     REAL NOT NULL,
+	seats_available INTEGER NOT NULL,
+	PRIMARY KEY (snapshot_id, fare_index, passenger_type)
+);
+`
+
+// PriceHistorian is implemented by backends able to return the price history
+// of a flight without replaying every archived revision.
+type PriceHistorian interface {
+	PriceHistory(origin, destination, date, flightNumber string, since time.Time) (map[time.Time]float64, error)
+}
+
+// SQLiteStorage keeps files in a SQLite database. Subscriptions and archived
+// flights are also indexed in their own tables, so that every observation of
+// a flight and its price is kept.
+type SQLiteStorage struct {
+	db               *sql.DB
+	SubscriptionsDir string
+	FlightsDir       string
+}
+
+func NewSQLite(filename string) (*SQLiteStorage, error) {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+	// sqlite does not support concurrent writers
+	db.SetMaxOpenConns(1)
+
+	rebuildPrices, err := dropLegacyFlightPrices(db)
+	if err == nil {
+		_, err = db.Exec(sqliteSchema)
+	}
+	if err == nil && rebuildPrices {
+		err = rebuildFlightPrices(db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create schema: %w", err)
+	}
+
+	return &SQLiteStorage{
+		db:               db,
+		SubscriptionsDir: "subscriptions",
+		FlightsDir:       "flights",
+	}, nil
+}
+
+func NewSQLiteFromEnv() (*SQLiteStorage, error) {
+	filename := os.Getenv("SQLITE_PATH")
+	if filename == "" {
+		filename = "wingo.db"
+	}
+
+	ss, err := NewSQLite(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir := os.Getenv("GH_PATH"); dir != "" {
+		ss.SubscriptionsDir = dir
+	}
+
+	return ss, nil
+}
+
+func (ss *SQLiteStorage) Close() error {
+	return ss.db.Close()
+}
+
+func (ss *SQLiteStorage) subscriptionUID(p string) (string, bool) {
+	dir, name := path.Split(p)
+	if path.Clean(dir) != path.Clean(ss.SubscriptionsDir) || path.Ext(name) != ".json" {
+		return "", false
+	}
+	return strings.TrimSuffix(name, ".json"), true
+}
+
+type flightKey struct {
+	origin, destination, date, flightNumber string
+}
+
+func (ss *SQLiteStorage) flightKey(p string) (flightKey, bool) {
+	parts := strings.Split(path.Clean(p), "/")
+	if len(parts) != 5 || parts[0] != path.Clean(ss.FlightsDir) || path.Ext(parts[4]) != ".json" {
+		return flightKey{}, false
+	}
+	return flightKey{parts[1], parts[2], parts[3], strings.TrimSuffix(parts[4], ".json")}, true
+}
+
+func (ss *SQLiteStorage) Read(path string) ([]byte, error) {
+	var content []byte
+	err := ss.db.QueryRow(`SELECT content FROM files WHERE path = ?`, path).Scan(&content)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %w", err)
+	}
+	return content, nil
+}
+
+func (ss *SQLiteStorage) Write(path string, b []byte, message string) error {
+	return ss.WriteAt(path, b, time.Now())
+}
+
+// WriteAt writes path as if it had been observed at the given time.
+func (ss *SQLiteStorage) WriteAt(path string, b []byte, observedAt time.Time) error {
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.Exec(`INSERT INTO files (path, content, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET content = excluded.content, updated_at = excluded.updated_at`,
+		path, b, observedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("could not write file: %w", err)
+	}
+
+	if uid, ok := ss.subscriptionUID(path); ok {
+		err = writeSubscription(tx, uid, b)
+	} else if key, ok := ss.flightKey(path); ok {
+		err = writeFlightSnapshot(tx, key, b, observedAt.UTC())
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// HasRevision reports whether the revision of path observed at the given
+// time was already written, for flights, or a revision as recent, for the
+// other files.
+func (ss *SQLiteStorage) HasRevision(path string, observedAt time.Time) (bool, error) {
+	var count int
+	var err error
+	if key, ok := ss.flightKey(path); ok {
+		err = ss.db.QueryRow(`SELECT COUNT(*) FROM flight_snapshots
+			WHERE origin = ? AND destination = ? AND date = ? AND flight_number = ? AND observed_at = ?`,
+			key.origin, key.destination, key.date, key.flightNumber, observedAt.UTC()).Scan(&count)
+	} else {
+		err = ss.db.QueryRow(`SELECT COUNT(*) FROM files WHERE path = ? AND updated_at >= ?`, path, observedAt.UTC()).Scan(&count)
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not query revision: %w", err)
+	}
+	return count != 0, nil
+}
+
+func writeSubscription(tx *sql.Tx, uid string, b []byte) error {
+	var setting struct {
+		Origin      string `json:"origin"`
+		Destination string `json:"destination"`
+		Date        string `json:"date"`
+		Email       string `json:"email"`
+		PhoneNumber string `json:"phone_number"`
+		Confirmed   bool   `json:"confirmed"`
+	}
+	err := json.Unmarshal(b, &setting)
+	if err != nil {
+		return fmt.Errorf("could not decode subscription: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO subscriptions (uid, origin, destination, date, email, phone_number, confirmed, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid) DO UPDATE SET origin = excluded.origin, destination = excluded.destination,
+			date = excluded.date, email = excluded.email, phone_number = excluded.phone_number,
+			confirmed = excluded.confirmed, data = excluded.data`,
+		uid, setting.Origin, setting.Destination, setting.Date, setting.Email, setting.PhoneNumber, setting.Confirmed, string(b))
+	if err != nil {
+		return fmt.Errorf("could not save subscription: %w", err)
+	}
+	return nil
+}
+
+// dropLegacyFlightPrices drops the flight_prices table of the databases
+// created when it only kept the price of a single adult in the basic bundle,
+// and reports whether it has to be rebuilt.
+func dropLegacyFlightPrices(db *sql.DB) (bool, error) {
+	var columns, bundleColumns int
+	err := db.QueryRow(`SELECT COUNT(*), COUNT(CASE WHEN name = 'bundle' THEN 1 END)
+		FROM pragma_table_info('flight_prices')`).Scan(&columns, &bundleColumns)
+	if err != nil || columns == 0 || bundleColumns != 0 {
+		return false, err
+	}
+
+	_, err = db.Exec(`DROP TABLE flight_prices`)
+	return err == nil, err
+}
+
+// rebuildFlightPrices fills flight_prices from the flight snapshots.
+func rebuildFlightPrices(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.Query(`SELECT id, origin, destination, date, flight_number, observed_at, data FROM flight_snapshots`)
+	if err != nil {
+		return fmt.Errorf("could not query flight snapshots: %w", err)
+	}
+
+	type snapshot struct {
+		id         int64
+		key        flightKey
+		observedAt time.Time
+		data       string
+	}
+	snapshots := []snapshot{}
+	for rows.Next() {
+		var s snapshot
+		err = rows.Scan(&s.id, &s.key.origin, &s.key.destination, &s.key.date, &s.key.flightNumber, &s.observedAt, &s.data)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		snapshots = append(snapshots, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range snapshots {
+		var flight archivedFlight
+		err = json.Unmarshal([]byte(s.data), &flight)
+		if err != nil {
+			return fmt.Errorf("could not decode archived flight: %w", err)
+		}
+
+		err = writeFlightPrices(tx, s.id, s.key, flight, s.observedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type archivedFlight struct {
+	wingo.Vuelo
+	Services []wingo.Service `json:"services"`
+}
+
+// writeFlightPrices keeps the price of every bundle for a single passenger of
+// each type, so that the price of any mix of passengers can be computed.
+func writeFlightPrices(tx *sql.Tx, id int64, key flightKey, flight archivedFlight, observedAt time.Time) error {
+	adminFares := wingo.GetAdminFares(wingo.ServiceQuote{Services: flight.Services})
+	for _, bundle := range wingo.Bundles {
+		for passengerType, passenger := range passengerTypes {
+			price, available := wingo.GetPassengerBundleFare(bundle, flight.Vuelo, flight.Services, passenger)
+			if !available {
+				continue
+			}
+
+			_, err := tx.Exec(`INSERT INTO flight_prices (snapshot_id, origin, destination, date, flight_number, observed_at,
+					bundle, passenger_type, price, admin_fares)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				id, key.origin, key.destination, key.date, key.flightNumber, observedAt, bundle, passengerType, price, adminFares)
+			if err != nil {
+				return fmt.Errorf("could not save flight price: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func writeFlightSnapshot(tx *sql.Tx, key flightKey, b []byte, observedAt time.Time) error {
+	var flight archivedFlight
+	err := json.Unmarshal(b, &flight)
+	if err != nil {
+		return fmt.Errorf("could not decode archived flight: %w", err)
+	}
+
+	result, err := tx.Exec(`INSERT INTO flight_snapshots (origin, destination, date, flight_number, observed_at, data)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		key.origin, key.destination, key.date, key.flightNumber, observedAt, string(b))
+	if err != nil {
+		return fmt.Errorf("could not save flight snapshot: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	err = writeFlightPrices(tx, id, key, flight, observedAt)
+	if err != nil {
+		return err
+	}
+
+	for i, infoFare := range flight.InfoFares {
+		for passengerType, fare := range map[string]wingo.Fare{
+			passengerAdult:  infoFare.FareAdult,
+			passengerChild:  infoFare.FareChild,
+			passengerInfant: infoFare.FareInfant,
+		} {
+			if fare.FareID == 0 && fare.FareAmount == 0 {
+				continue
+			}
+
+			_, err = tx.Exec(`INSERT INTO flight_seats (snapshot_id, fare_index, passenger_type, fare_id, fare_amount, seats_available)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				id, i, passengerType, fare.FareID, fare.FareAmount, fare.SeatsAvailable)
+			if err != nil {
+				return fmt.Errorf("could not save flight seats: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (ss *SQLiteStorage) Delete(path string, message string) error {
+	result, err := ss.db.Exec(`DELETE FROM files WHERE path = ?`, path)
+	if err != nil {
+		return fmt.Errorf("could not delete file: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+
+	if uid, ok := ss.subscriptionUID(path); ok {
+		_, err = ss.db.Exec(`DELETE FROM subscriptions WHERE uid = ?`, uid)
+		if err != nil {
+			return fmt.Errorf("could not delete subscription: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (ss *SQLiteStorage) List(dir string) ([]string, error) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+
+	rows, err := ss.db.Query(`SELECT path FROM files WHERE path LIKE ? ESCAPE '\'`, escaped+"%")
+	if err != nil {
+		return nil, fmt.Errorf("could not list files: %w", err)
+	}
+	defer rows.Close()
+
+	seen := map[string]bool{}
+	names := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(p, prefix)
+		if i := strings.Index(name, "/"); i != -1 {
+			name = name[:i]
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, rows.Err()
+}
+
+func (ss *SQLiteStorage) History(path string, since time.Time) ([]Revision, error) {
+	key, ok := ss.flightKey(path)
+	if !ok {
+		var content []byte
+		var updatedAt time.Time
+		err := ss.db.QueryRow(`SELECT content, updated_at FROM files WHERE path = ? AND updated_at >= ?`,
+			path, since.UTC()).Scan(&content, &updatedAt)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []Revision{{Date: updatedAt, Content: content}}, nil
+	}
+
+	rows, err := ss.db.Query(`SELECT observed_at, data FROM flight_snapshots
+		WHERE origin = ? AND destination = ? AND date = ? AND flight_number = ? AND observed_at >= ?
+		ORDER BY observed_at`,
+		key.origin, key.destination, key.date, key.flightNumber, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("could not query flight snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []Revision{}
+	for rows.Next() {
+		var revision Revision
+		var data string
+		if err := rows.Scan(&revision.Date, &data); err != nil {
+			return nil, err
+		}
+		revision.Content = []byte(data)
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// PriceHistory returns the price of a flight for a single adult in the basic
+// bundle in every observation made after since.
+func (ss *SQLiteStorage) PriceHistory(origin, destination, date, flightNumber string, since time.Time) (map[time.Time]float64, error) {
+	return ss.BundlePriceHistory(origin, destination, date, flightNumber, wingo.BundleBasic, wingo.SingleAdult, since)
+}
+
+// BundlePriceHistory returns the price of a flight for the passengers in the
+// given bundle, including the admin fares, in every observation made after
+// since where it was available.
+func (ss *SQLiteStorage) BundlePriceHistory(origin, destination, date, flightNumber, bundle string, passengers wingo.Passengers, since time.Time) (map[time.Time]float64, error) {
+	rows, err := ss.db.Query(`SELECT observed_at, passenger_type, price, admin_fares FROM flight_prices
+		WHERE origin = ? AND destination = ? AND date = ? AND flight_number = ? AND bundle = ? AND observed_at >= ?`,
+		origin, destination, date, flightNumber, wingo.NormalizeBundle(bundle), since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("could not query flight prices: %w", err)
+	}
+	defer rows.Close()
+
+	type observation struct {
+		prices     map[string]float64
+		adminFares float64
+	}
+	observations := map[time.Time]*observation{}
+	for rows.Next() {
+		var observedAt time.Time
+		var passengerType string
+		var price, adminFares float64
+		if err := rows.Scan(&observedAt, &passengerType, &price, &adminFares); err != nil {
+			return nil, err
+		}
+
+		o := observations[observedAt]
+		if o == nil {
+			o = &observation{prices: map[string]float64{}, adminFares: adminFares}
+			observations[observedAt] = o
+		}
+		o.prices[passengerType] = price
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	passengers = passengers.OrDefault()
+	counts := map[string]int{
+		passengerAdult:  passengers.Adults,
+		passengerChild:  passengers.Children,
+		passengerInfant: passengers.Infants,
+	}
+
+	prices := map[time.Time]float64{}
+	for observedAt, o := range observations {
+		total, available := o.adminFares, true
+		for passengerType, count := range counts {
+			price, found := o.prices[passengerType]
+			if count != 0 && !found {
+				available = false
+				break
+			}
+			total += float64(count) * price
+		}
+		if available {
+			prices[observedAt] = total
+		}
+	}
+
+	return prices, nil
+}
+
+// SeatsHistory returns the seats available at the cheapest adult fare of a
+// flight in every observation made after since.
+func (ss *SQLiteStorage) SeatsHistory(origin, destination, date, flightNumber string, since time.Time) (map[time.Time]int64, error) {
+	rows, err := ss.db.Query(`SELECT s.observed_at, f.seats_available FROM flight_seats f
+		JOIN flight_snapshots s ON s.id = f.snapshot_id
+		WHERE s.origin = ? AND s.destination = ? AND s.date = ? AND s.flight_number = ? AND s.observed_at >= ?
+			AND f.fare_index = 0 AND f.passenger_type = ?`,
+		origin, destination, date, flightNumber, since.UTC(), passengerAdult)
+	if err != nil {
+		return nil, fmt.Errorf("could not query flight seats: %w", err)
+	}
+	defer rows.Close()
+
+	seats := map[time.Time]int64{}
+	for rows.Next() {
+		var observedAt time.Time
+		var available int64
+		if err := rows.Scan(&observedAt, &available); err != nil {
+			return nil, err
+		}
+		seats[observedAt] = available
+	}
+
+	return seats, rows.Err()
+}
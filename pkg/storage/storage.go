@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	KindGithub = "github"
+	KindLocal  = "local"
+	KindMemory = "memory"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Revision is the content of a file at a given point in time.
+type Revision struct {
+	Date    time.Time
+	Content []byte
+}
+
+// Backend stores files identified by slash separated paths.
+type Backend interface {
+	Read(path string) ([]byte, error)
+	Write(path string, b []byte, message string) error
+	Delete(path string, message string) error
+	// List returns the names of the entries directly under dir. A missing
+	// directory has no entries.
+	List(dir string) ([]string, error)
+	// History returns the revisions of path written after since.
+	History(path string, since time.Time) ([]Revision, error)
+}
+
+// NewFromEnv creates the backend selected by STORAGE_BACKEND, using fallback
+// when it is not set.
+func NewFromEnv(fallback string) (Backend, error) {
+	kind := os.Getenv("STORAGE_BACKEND")
+	if kind == "" {
+		kind = fallback
+	}
+
+	switch kind {
+	case KindGithub:
+		return NewGithubFromEnv()
+	case KindLocal:
+		return NewLocalFromEnv(), nil
+	case KindMemory:
+		return NewMemory(), nil
+	case KindSQLite:
+		return NewSQLiteFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", kind)
+	}
+}
+
+// Close releases the resources held by backend, like the database of the
+// SQLite backend. The other backends hold none.
+func Close(backend Backend) error {
+	if closer, ok := backend.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
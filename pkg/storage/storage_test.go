@@ -0,0 +1,238 @@
+package storage_test
+
+import (
+	"database/sql"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabianMendez/wingo"
+	"github.com/fabianMendez/wingo/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackends(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend storage.Backend
+	}{
+		{name: "memory", backend: storage.NewMemory()},
+		{name: "local", backend: storage.NewLocal(t.TempDir())},
+		{name: "sqlite", backend: newSQLite(t)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since := time.Now().Add(-time.Minute)
+
+			_, err := tt.backend.Read("flights/BOG/CTG/2022-01-01/7013.json")
+			assert.ErrorIs(t, err, storage.ErrNotFound)
+
+			err = tt.backend.Write("flights/BOG/CTG/2022-01-01/7013.json", []byte(`{"flightNumber":"7013"}`), "add")
+			require.NoError(t, err)
+			err = tt.backend.Write("flights/BOG/CTG/2022-01-02/7015.json", []byte(`{"flightNumber":"7015"}`), "add")
+			require.NoError(t, err)
+
+			content, err := tt.backend.Read("flights/BOG/CTG/2022-01-01/7013.json")
+			require.NoError(t, err)
+			assert.Equal(t, `{"flightNumber":"7013"}`, string(content))
+
+			names, err := tt.backend.List("flights/BOG/CTG")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"2022-01-01", "2022-01-02"}, names)
+
+			names, err = tt.backend.List("flights/BOG/SMR")
+			require.NoError(t, err)
+			assert.Empty(t, names)
+
+			revisions, err := tt.backend.History("flights/BOG/CTG/2022-01-01/7013.json", since)
+			require.NoError(t, err)
+			require.Len(t, revisions, 1)
+			assert.Equal(t, `{"flightNumber":"7013"}`, string(revisions[0].Content))
+
+			err = tt.backend.Delete("flights/BOG/CTG/2022-01-01/7013.json", "remove")
+			require.NoError(t, err)
+			_, err = tt.backend.Read("flights/BOG/CTG/2022-01-01/7013.json")
+			assert.ErrorIs(t, err, storage.ErrNotFound)
+		})
+	}
+}
+
+func TestMemoryHistory(t *testing.T) {
+	backend := storage.NewMemory()
+	since := time.Now()
+
+	require.NoError(t, backend.Write("a.json", []byte("1"), ""))
+	require.NoError(t, backend.Write("a.json", []byte("2"), ""))
+
+	revisions, err := backend.History("a.json", since)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "1", string(revisions[0].Content))
+	assert.Equal(t, "2", string(revisions[1].Content))
+}
+
+func TestSQLitePriceHistory(t *testing.T) {
+	backend, err := storage.NewSQLite(filepath.Join(t.TempDir(), "wingo.db"))
+	require.NoError(t, err)
+	defer backend.Close()
+
+	flight := `{"flightNumber":"7013","infoFares":[{"fareAdult":{"fareID":1,"seatsAvailable":4,"fareAmount":100,"applicableTaxes":[{"taxAmount":20}]},` +
+		`"fareChild":{"fareID":2,"fareAmount":80}}],"services":[{"codeType":"BFEE","amount":10},{"codeType":"CLAS","amount":40}]}`
+	first := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	require.NoError(t, backend.WriteAt("flights/BOG/CTG/2022-02-01/7013.json", []byte(flight), first))
+	require.NoError(t, backend.WriteAt("flights/BOG/CTG/2022-02-01/7013.json", []byte(flight), second))
+	require.NoError(t, backend.Write("subscriptions/abc.json", []byte(`{"origin":"BOG","destination":"CTG"}`), "add"))
+
+	prices, err := backend.PriceHistory("BOG", "CTG", "2022-02-01", "7013", first)
+	require.NoError(t, err)
+	assert.Equal(t, map[time.Time]float64{first: 130, second: 130}, prices)
+
+	prices, err = backend.BundlePriceHistory("BOG", "CTG", "2022-02-01", "7013", wingo.BundleClassic, wingo.Passengers{Adults: 2, Children: 1}, second)
+	require.NoError(t, err)
+	assert.Equal(t, map[time.Time]float64{second: 2*160 + 120 + 10}, prices)
+
+	prices, err = backend.BundlePriceHistory("BOG", "CTG", "2022-02-01", "7013", wingo.BundleBasic, wingo.Passengers{Adults: 1, Infants: 1}, first)
+	require.NoError(t, err)
+	assert.Empty(t, prices, "the infant fare is not available")
+
+	prices, err = backend.BundlePriceHistory("BOG", "CTG", "2022-02-01", "7013", wingo.BundlePlus, wingo.SingleAdult, first)
+	require.NoError(t, err)
+	assert.Empty(t, prices, "the plus bundle is not available")
+
+	seats, err := backend.SeatsHistory("BOG", "CTG", "2022-02-01", "7013", first)
+	require.NoError(t, err)
+	assert.Equal(t, map[time.Time]int64{first: 4, second: 4}, seats)
+
+	revisions, err := backend.History("flights/BOG/CTG/2022-02-01/7013.json", second)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.True(t, second.Equal(revisions[0].Date))
+
+	names, err := backend.List("subscriptions")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abc.json"}, names)
+}
+
+func TestSQLiteLegacyFlightPrices(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "wingo.db")
+	db, err := sql.Open("sqlite", filename)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE flight_snapshots (id INTEGER PRIMARY KEY AUTOINCREMENT, origin TEXT NOT NULL,
+			destination TEXT NOT NULL, date TEXT NOT NULL, flight_number TEXT NOT NULL, observed_at TIMESTAMP NOT NULL, data TEXT NOT NULL);
+		CREATE TABLE flight_prices (snapshot_id INTEGER PRIMARY KEY, origin TEXT NOT NULL, destination TEXT NOT NULL,
+			date TEXT NOT NULL, flight_number TEXT NOT NULL, observed_at TIMESTAMP NOT NULL, price REAL NOT NULL);`)
+	require.NoError(t, err)
+	observedAt := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	_, err = db.Exec(`INSERT INTO flight_snapshots (origin, destination, date, flight_number, observed_at, data) VALUES (?, ?, ?, ?, ?, ?)`,
+		"BOG", "CTG", "2022-02-01", "7013", observedAt, `{"infoFares":[{"fareAdult":{"fareID":1,"fareAmount":100}}]}`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	backend, err := storage.NewSQLite(filename)
+	require.NoError(t, err)
+	defer backend.Close()
+
+	prices, err := backend.PriceHistory("BOG", "CTG", "2022-02-01", "7013", observedAt)
+	require.NoError(t, err)
+	assert.Equal(t, map[time.Time]float64{observedAt: 100}, prices, "the prices are rebuilt from the snapshots")
+}
+
+func TestGitHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(date time.Time, args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		// the history is dated by the commits, not by their authors
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2020-01-01T00:00:00Z", "GIT_COMMITTER_DATE="+date.Format(time.RFC3339))
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	first := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	backend := storage.NewGit(dir)
+	git(first, "init", "-q")
+	require.NoError(t, backend.Write("flights/BOG/CTG/2022-02-01/7013.json", []byte("1"), ""))
+	git(first, "add", "-A")
+	git(first, "commit", "-q", "-m", "first")
+	require.NoError(t, backend.Write("flights/BOG/CTG/2022-02-01/7013.json", []byte("2"), ""))
+	git(second, "commit", "-q", "-a", "-m", "second")
+
+	revisions, err := backend.History("flights/BOG/CTG/2022-02-01/7013.json", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "1", string(revisions[0].Content))
+	assert.Equal(t, first, revisions[0].Date)
+	assert.Equal(t, "2", string(revisions[1].Content))
+	assert.Equal(t, second, revisions[1].Date)
+
+	revisions, err = backend.History("flights/BOG/CTG/2022-02-01/7013.json", first.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "2", string(revisions[0].Content))
+
+	revisions, err = backend.History("flights/BOG/CTG/2022-02-01/7014.json", time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	require.NoError(t, backend.Delete("flights/BOG/CTG/2022-02-01/7013.json", ""))
+	git(second.Add(time.Hour), "commit", "-q", "-a", "-m", "deleted")
+	paths, err := backend.Paths("flights")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"flights/BOG/CTG/2022-02-01/7013.json"}, paths, "the deleted files are listed")
+	revisions, err = backend.History("flights/BOG/CTG/2022-02-01/7013.json", time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, revisions, 2)
+}
+
+func TestSQLiteHasRevision(t *testing.T) {
+	backend, err := storage.NewSQLite(filepath.Join(t.TempDir(), "wingo.db"))
+	require.NoError(t, err)
+	defer backend.Close()
+
+	observedAt := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, backend.WriteAt("flights/BOG/CTG/2022-02-01/7013.json", []byte(`{"flightNumber":"7013"}`), observedAt))
+	require.NoError(t, backend.WriteAt("subscriptions/abc.json", []byte(`{}`), observedAt))
+
+	for _, tt := range []struct {
+		path       string
+		observedAt time.Time
+		expected   bool
+	}{
+		{"flights/BOG/CTG/2022-02-01/7013.json", observedAt, true},
+		{"flights/BOG/CTG/2022-02-01/7013.json", observedAt.Add(-time.Hour), false},
+		{"flights/BOG/CTG/2022-02-01/7014.json", observedAt, false},
+		{"subscriptions/abc.json", observedAt.Add(-time.Hour), true},
+		{"subscriptions/abc.json", observedAt.Add(time.Hour), false},
+	} {
+		present, err := backend.HasRevision(tt.path, tt.observedAt)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, present, tt.path, tt.observedAt)
+	}
+}
+
+func TestClose(t *testing.T) {
+	assert.NoError(t, storage.Close(storage.NewMemory()))
+
+	backend, err := storage.NewSQLite(filepath.Join(t.TempDir(), "wingo.db"))
+	require.NoError(t, err)
+	require.NoError(t, storage.Close(backend))
+	assert.Error(t, backend.Write("a.json", []byte("1"), ""), "the database is closed")
+}
+
+func newSQLite(t *testing.T) storage.Backend {
+	backend, err := storage.NewSQLite(filepath.Join(t.TempDir(), "wingo.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { backend.Close() })
+	return backend
+}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const DefaultBaseURL = "https://api.telegram.org"
+
+const ParseModeMarkdownV2 = "MarkdownV2"
+
+// Client sends messages through the Telegram Bot API.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func NewClient(token string) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Token: token, HTTPClient: http.DefaultClient}
+}
+
+// NewClientFromEnv creates a client for the bot of TELEGRAM_BOT_TOKEN.
+// TELEGRAM_API_URL overrides the URL of the Bot API.
+func NewClientFromEnv() *Client {
+	client := NewClient(os.Getenv("TELEGRAM_BOT_TOKEN"))
+	if baseURL := os.Getenv("TELEGRAM_API_URL"); baseURL != "" {
+		client.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	return client
+}
+
+type sendMessageRequest struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode,omitempty"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview,omitempty"`
+}
+
+type response struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+// SendMessage sends text to the chat. parseMode can be empty for plain text.
+func (c *Client) SendMessage(ctx context.Context, chatID, text, parseMode string) error {
+	if c.Token == "" {
+		return fmt.Errorf("telegram bot token not set")
+	}
+
+	body, err := json.Marshal(sendMessageRequest{
+		ChatID:                chatID,
+		Text:                  text,
+		ParseMode:             parseMode,
+		DisableWebPagePreview: true,
+	})
+	if err != nil {
+		return fmt.Errorf("could not encode request: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/bot%s/sendMessage", c.BaseURL, c.Token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result response
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return fmt.Errorf("could not decode response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if !result.OK {
+		return fmt.Errorf("telegram error %d: %s", result.ErrorCode, result.Description)
+	}
+	return nil
+}
+
+// Update is an incoming update of the bot, as received by its webhook.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type User struct {
+	ID           int64  `json:"id"`
+	LanguageCode string `json:"language_code"`
+}
+
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, `_`, `\_`, `*`, `\*`, `[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`, `~`, `\~`, "`", "\\`",
+	`>`, `\>`, `#`, `\#`, `+`, `\+`, `-`, `\-`, `=`, `\=`, `|`, `\|`, `{`, `\{`, `}`, `\}`, `.`, `\.`, `!`, `\!`,
+)
+
+// EscapeMarkdownV2 escapes the characters reserved by the MarkdownV2 parse
+// mode.
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+var markdownV2URLReplacer = strings.NewReplacer(`\`, `\\`, `)`, `\)`)
+
+// LinkMarkdownV2 returns an inline link in MarkdownV2.
+func LinkMarkdownV2(text, u string) string {
+	return "[" + EscapeMarkdownV2(text) + "](" + markdownV2URLReplacer.Replace(u) + ")"
+}
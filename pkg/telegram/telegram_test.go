@@ -0,0 +1,47 @@
+package telegram_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fabianMendez/wingo/pkg/telegram"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendMessage(t *testing.T) {
+	var path string
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if request["chat_id"] == "0" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer server.Close()
+
+	client := telegram.NewClient("123:abc")
+	client.BaseURL = server.URL
+
+	err := client.SendMessage(context.Background(), "42", "*hola*", telegram.ParseModeMarkdownV2)
+	require.NoError(t, err)
+	assert.Equal(t, "/bot123:abc/sendMessage", path)
+	assert.Equal(t, "42", request["chat_id"])
+	assert.Equal(t, "MarkdownV2", request["parse_mode"])
+
+	err = client.SendMessage(context.Background(), "0", "hola", "")
+	assert.EqualError(t, err, "telegram error 400: Bad Request: chat not found")
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	assert.Equal(t, `↘️ El precio BAJÓ a $90\.000 \(desde $100\.000\)\.`,
+		telegram.EscapeMarkdownV2("↘️ El precio BAJÓ a $90.000 (desde $100.000)."))
+	assert.Equal(t, `[Ver \- vuelo](https://wingo.com/a_(b\))`, telegram.LinkMarkdownV2("Ver - vuelo", "https://wingo.com/a_(b)"))
+}
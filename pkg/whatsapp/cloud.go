@@ -0,0 +1,161 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const DefaultCloudBaseURL = "https://graph.facebook.com/v19.0"
+
+// CloudClient sends messages through the WhatsApp Business Cloud API. When
+// Template is set, SendMessage sends that template with the subject and the
+// message as its body parameters, as required to start conversations. The
+// template is sent in Language unless the recipient has one.
+type CloudClient struct {
+	BaseURL       string
+	PhoneNumberID string
+	AccessToken   string
+	CountryCode   string
+	Template      string
+	Language      string
+	HTTPClient    *http.Client
+}
+
+func NewCloudClient(phoneNumberID, accessToken string) *CloudClient {
+	return &CloudClient{
+		BaseURL:       DefaultCloudBaseURL,
+		PhoneNumberID: phoneNumberID,
+		AccessToken:   accessToken,
+		CountryCode:   DefaultCountryCode,
+		Language:      "es",
+		HTTPClient:    http.DefaultClient,
+	}
+}
+
+type cloudText struct {
+	Body       string `json:"body"`
+	PreviewURL bool   `json:"preview_url"`
+}
+
+type cloudParameter struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type cloudComponent struct {
+	Type       string           `json:"type"`
+	Parameters []cloudParameter `json:"parameters"`
+}
+
+type cloudLanguage struct {
+	Code string `json:"code"`
+}
+
+type cloudTemplate struct {
+	Name       string           `json:"name"`
+	Language   cloudLanguage    `json:"language"`
+	Components []cloudComponent `json:"components,omitempty"`
+}
+
+type cloudMessage struct {
+	MessagingProduct string         `json:"messaging_product"`
+	To               string         `json:"to"`
+	Type             string         `json:"type"`
+	Text             *cloudText     `json:"text,omitempty"`
+	Template         *cloudTemplate `json:"template,omitempty"`
+}
+
+type cloudErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+func (c *CloudClient) SendMessage(ctx context.Context, to, subject, message string) error {
+	return c.SendLocalizedMessage(ctx, to, "", subject, message)
+}
+
+func (c *CloudClient) SendLocalizedMessage(ctx context.Context, to, language, subject, message string) error {
+	if c.Template != "" {
+		return c.SendTemplate(ctx, to, Template{
+			Name:       c.Template,
+			Language:   language,
+			Parameters: []string{subject, message},
+		})
+	}
+
+	return c.send(ctx, to, cloudMessage{
+		Type: "text",
+		Text: &cloudText{Body: fmt.Sprintf("*%s*\n\n%s", subject, message)},
+	})
+}
+
+func (c *CloudClient) SendTemplate(ctx context.Context, to string, template Template) error {
+	language := template.Language
+	if language == "" {
+		language = c.Language
+	}
+
+	tpl := &cloudTemplate{Name: template.Name, Language: cloudLanguage{Code: language}}
+	if len(template.Parameters) != 0 {
+		body := cloudComponent{Type: "body"}
+		for _, parameter := range template.Parameters {
+			body.Parameters = append(body.Parameters, cloudParameter{Type: "text", Text: templateParameter(parameter)})
+		}
+		tpl.Components = []cloudComponent{body}
+	}
+
+	return c.send(ctx, to, cloudMessage{Type: "template", Template: tpl})
+}
+
+// templateParameter removes the new lines and tabs, which are not allowed
+// in the parameters of the templates.
+func templateParameter(s string) string {
+	lines := strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == '\r' || r == '\t' })
+	for i := range lines {
+		lines[i] = strings.Join(strings.Fields(lines[i]), " ")
+	}
+	return strings.Join(lines, " · ")
+}
+
+func (c *CloudClient) send(ctx context.Context, to string, msg cloudMessage) error {
+	to, err := NormalizePhoneNumber(to, c.CountryCode)
+	if err != nil {
+		return err
+	}
+
+	msg.MessagingProduct = "whatsapp"
+	msg.To = strings.TrimPrefix(to, "+")
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not encode request: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/%s/messages", c.BaseURL, c.PhoneNumberID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp cloudErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return &APIError{StatusCode: resp.StatusCode, Code: errResp.Error.Code, Message: errResp.Error.Message}
+	}
+	return nil
+}
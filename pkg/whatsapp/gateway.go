@@ -0,0 +1,74 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// GatewayClient sends free text messages through the custom gateway, which
+// receives {to, message} in POST <URL>/send.
+type GatewayClient struct {
+	URL         string
+	Token       string
+	CountryCode string
+	HTTPClient  *http.Client
+}
+
+func NewGatewayClient(u, token string) *GatewayClient {
+	return &GatewayClient{
+		URL:         strings.TrimSuffix(u, "/"),
+		Token:       token,
+		CountryCode: DefaultCountryCode,
+		HTTPClient:  http.DefaultClient,
+	}
+}
+
+func (c *GatewayClient) SendMessage(ctx context.Context, to, subject, message string) error {
+	to, err := NormalizePhoneNumber(to, c.CountryCode)
+	if err != nil {
+		return err
+	}
+
+	request := struct {
+		To      string `json:"to"`
+		Message string `json:"message"`
+	}{
+		To:      to,
+		Message: fmt.Sprintf("*%s*\n\n%s", subject, message),
+	}
+
+	body := new(bytes.Buffer)
+	err = json.NewEncoder(body).Encode(request)
+	if err != nil {
+		return fmt.Errorf("could not encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL+"/send", body)
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "token "+c.Token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(b))}
+	}
+	return nil
+}
+
+func (c *GatewayClient) SendTemplate(ctx context.Context, to string, template Template) error {
+	return ErrTemplatesNotSupported
+}
@@ -0,0 +1,53 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCountryCode is the calling code of the numbers without one.
+const DefaultCountryCode = "57"
+
+// nationalNumberLength is the length of the Colombian mobile numbers.
+const nationalNumberLength = 10
+
+// NormalizePhoneNumber returns the E.164 form (e.g. "+573001234567") of
+// a phone number. Numbers without a calling code ("+" or "00" prefix) are
+// considered national numbers of countryCode, unless they are longer than
+// a national number and already start with it.
+func NormalizePhoneNumber(phone, countryCode string) (string, error) {
+	original := phone
+	phone = strings.TrimSpace(phone)
+
+	international := false
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		international = true
+		phone = phone[1:]
+	case strings.HasPrefix(phone, "00"):
+		international = true
+		phone = phone[2:]
+	}
+
+	digits := new(strings.Builder)
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("invalid phone number: %s", original)
+		}
+	}
+
+	number := digits.String()
+	countryCode = strings.TrimPrefix(countryCode, "+")
+	if !international && !(strings.HasPrefix(number, countryCode) && len(number) > nationalNumberLength) {
+		number = countryCode + strings.TrimLeft(number, "0")
+	}
+
+	if len(number) < 8 || len(number) > 15 {
+		return "", fmt.Errorf("invalid phone number: %s", original)
+	}
+	return "+" + number, nil
+}
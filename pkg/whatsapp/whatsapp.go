@@ -1,52 +1,109 @@
 package whatsapp
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
 	"os"
+	"strings"
 )
 
-func SendMessage(to, subject, message string) error {
-	waurl := os.Getenv("WA_URL")
-	watoken := os.Getenv("WA_TOKEN")
-	if waurl == "" {
-		log.Print("Whatsapp URL not set")
-		return nil
-	}
-	if watoken == "" {
-		log.Print("Whatsapp token not set")
-		return nil
-	}
+const (
+	ProviderGateway = "gateway"
+	ProviderCloud   = "cloud"
+)
 
-	request := struct {
-		To      string `json:"to"`
-		Message string `json:"message"`
-	}{
-		To:      to,
-		Message: fmt.Sprintf("*%s*\n\n%s", subject, message),
-	}
+// ErrNotConfigured is returned by NewClientFromEnv when the environment has
+// no WhatsApp configuration.
+var ErrNotConfigured = errors.New("whatsapp is not configured")
+
+// ErrTemplatesNotSupported is returned by the clients that can not send
+// template messages.
+var ErrTemplatesNotSupported = errors.New("template messages are not supported")
+
+// Template is a message template approved in WhatsApp Business, with the
+// values of its body parameters.
+type Template struct {
+	Name       string
+	Language   string
+	Parameters []string
+}
+
+// Client sends WhatsApp messages. The phone numbers are normalized to E.164
+// before sending.
+type Client interface {
+	SendMessage(ctx context.Context, to, subject, message string) error
+	SendTemplate(ctx context.Context, to string, template Template) error
+}
 
-	body := new(bytes.Buffer)
-	err := json.NewEncoder(body).Encode(request)
-	if err != nil {
-		return fmt.Errorf("could not encode request: %w", err)
+// LocalizedClient is implemented by the clients whose messages depend on the
+// language of the recipient, like the templates of the Cloud API.
+type LocalizedClient interface {
+	SendLocalizedMessage(ctx context.Context, to, language, subject, message string) error
+}
+
+// APIError is an error response of the WhatsApp API or gateway.
+type APIError struct {
+	StatusCode int
+	Code       int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("whatsapp responded with status %d: %s (code %d)", e.StatusCode, e.Message, e.Code)
 	}
+	if e.Message != "" {
+		return fmt.Sprintf("whatsapp responded with status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("whatsapp responded with status %d", e.StatusCode)
+}
 
-	req, err := http.NewRequest(http.MethodPost, waurl+"/send", body)
-	if err != nil {
-		return fmt.Errorf("could not create request: %w", err)
+// NewClientFromEnv creates the client chosen by WA_PROVIDER: the custom
+// gateway of WA_URL and WA_TOKEN (the default), or the WhatsApp Business
+// Cloud API of WA_PHONE_NUMBER_ID and WA_ACCESS_TOKEN.
+func NewClientFromEnv() (Client, error) {
+	countryCode := os.Getenv("WA_DEFAULT_COUNTRY_CODE")
+	if countryCode == "" {
+		countryCode = DefaultCountryCode
 	}
 
-	req.Header.Set("Authorization", "token "+watoken)
+	switch provider := strings.ToLower(os.Getenv("WA_PROVIDER")); provider {
+	case "", ProviderGateway:
+		waurl, watoken := os.Getenv("WA_URL"), os.Getenv("WA_TOKEN")
+		if waurl == "" && watoken == "" && provider == "" {
+			return nil, ErrNotConfigured
+		}
+		if waurl == "" {
+			return nil, errors.New("whatsapp URL not set")
+		}
+		if watoken == "" {
+			return nil, errors.New("whatsapp token not set")
+		}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("could not send request: %w", err)
-	}
-	defer resp.Body.Close()
+		client := NewGatewayClient(waurl, watoken)
+		client.CountryCode = countryCode
+		return client, nil
+	case ProviderCloud:
+		phoneNumberID, accessToken := os.Getenv("WA_PHONE_NUMBER_ID"), os.Getenv("WA_ACCESS_TOKEN")
+		if phoneNumberID == "" {
+			return nil, errors.New("whatsapp phone number id not set")
+		}
+		if accessToken == "" {
+			return nil, errors.New("whatsapp access token not set")
+		}
 
-	return nil
+		client := NewCloudClient(phoneNumberID, accessToken)
+		client.CountryCode = countryCode
+		if baseURL := os.Getenv("WA_API_URL"); baseURL != "" {
+			client.BaseURL = strings.TrimSuffix(baseURL, "/")
+		}
+		client.Template = os.Getenv("WA_TEMPLATE")
+		if language := os.Getenv("WA_TEMPLATE_LANGUAGE"); language != "" {
+			client.Language = language
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown whatsapp provider: %s", provider)
+	}
 }
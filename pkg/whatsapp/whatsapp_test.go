@@ -0,0 +1,142 @@
+package whatsapp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fabianMendez/wingo/internal/testenv"
+	"github.com/fabianMendez/wingo/pkg/whatsapp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		phone    string
+		expected string
+	}{
+		{"3001234567", "+573001234567"},
+		{"300 123 4567", "+573001234567"},
+		{"+57 (300) 123-4567", "+573001234567"},
+		{"573001234567", "+573001234567"},
+		{"0057 300 123 4567", "+573001234567"},
+		{"+1 415 555 2671", "+14155552671"},
+	}
+
+	for _, tt := range tests {
+		actual, err := whatsapp.NormalizePhoneNumber(tt.phone, whatsapp.DefaultCountryCode)
+		require.NoError(t, err, tt.phone)
+		assert.Equal(t, tt.expected, actual, tt.phone)
+	}
+
+	for _, phone := range []string{"", "123", "+57 300 abc 4567", "+1234567890123456"} {
+		_, err := whatsapp.NormalizePhoneNumber(phone, whatsapp.DefaultCountryCode)
+		assert.Error(t, err, phone)
+	}
+}
+
+func TestGatewayClient(t *testing.T) {
+	var request map[string]string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/send", r.URL.Path)
+		assert.Equal(t, "token t0k3n", r.Header.Get("Authorization"))
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("session closed"))
+	}))
+	defer server.Close()
+
+	client := whatsapp.NewGatewayClient(server.URL, "t0k3n")
+
+	require.NoError(t, client.SendMessage(context.Background(), "300 123 4567", "BOG-CTG", "Precio actual: $100."))
+	assert.Equal(t, "+573001234567", request["to"])
+	assert.Equal(t, "*BOG-CTG*\n\nPrecio actual: $100.", request["message"])
+
+	status = http.StatusServiceUnavailable
+	err := client.SendMessage(context.Background(), "3001234567", "BOG-CTG", "Precio actual: $100.")
+	var apiErr *whatsapp.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, "session closed", apiErr.Message)
+
+	assert.ErrorIs(t, client.SendTemplate(context.Background(), "3001234567", whatsapp.Template{Name: "price"}), whatsapp.ErrTemplatesNotSupported)
+}
+
+func TestCloudClientTemplate(t *testing.T) {
+	var request struct {
+		MessagingProduct string `json:"messaging_product"`
+		To               string `json:"to"`
+		Type             string `json:"type"`
+		Template         struct {
+			Name     string `json:"name"`
+			Language struct {
+				Code string `json:"code"`
+			} `json:"language"`
+			Components []struct {
+				Type       string `json:"type"`
+				Parameters []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"parameters"`
+			} `json:"components"`
+		} `json:"template"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/123/messages", r.URL.Path)
+		assert.Equal(t, "Bearer acc3ss", r.Header.Get("Authorization"))
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if request.To == "14155552671" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Template name does not exist","code":132001}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	client := whatsapp.NewCloudClient("123", "acc3ss")
+	client.BaseURL = server.URL
+	client.Template = "price_alert"
+
+	err := client.SendMessage(context.Background(), "+57 300 123 4567", "BOG-CTG", "El precio BAJÓ.\nEquipaje: $50.")
+	require.NoError(t, err)
+	assert.Equal(t, "whatsapp", request.MessagingProduct)
+	assert.Equal(t, "573001234567", request.To)
+	assert.Equal(t, "template", request.Type)
+	assert.Equal(t, "price_alert", request.Template.Name)
+	assert.Equal(t, "es", request.Template.Language.Code)
+	require.Len(t, request.Template.Components, 1)
+	require.Len(t, request.Template.Components[0].Parameters, 2)
+	assert.Equal(t, "El precio BAJÓ. · Equipaje: $50.", request.Template.Components[0].Parameters[1].Text)
+
+	err = client.SendLocalizedMessage(context.Background(), "3001234567", "en", "BOG-CTG", "The price DROPPED.")
+	require.NoError(t, err)
+	assert.Equal(t, "en", request.Template.Language.Code, "the language of the recipient is used")
+
+	err = client.SendMessage(context.Background(), "+1 415 555 2671", "BOG-CTG", "El precio BAJÓ.")
+	assert.EqualError(t, err, "whatsapp responded with status 400: Template name does not exist (code 132001)")
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	testenv.Setenv(t, "WA_PROVIDER", "")
+	testenv.Setenv(t, "WA_URL", "")
+	testenv.Setenv(t, "WA_TOKEN", "")
+	_, err := whatsapp.NewClientFromEnv()
+	assert.ErrorIs(t, err, whatsapp.ErrNotConfigured)
+
+	testenv.Setenv(t, "WA_URL", "https://wa.example.com")
+	_, err = whatsapp.NewClientFromEnv()
+	assert.EqualError(t, err, "whatsapp token not set")
+
+	testenv.Setenv(t, "WA_PROVIDER", "cloud")
+	testenv.Setenv(t, "WA_PHONE_NUMBER_ID", "123")
+	testenv.Setenv(t, "WA_ACCESS_TOKEN", "acc3ss")
+	client, err := whatsapp.NewClientFromEnv()
+	require.NoError(t, err)
+	assert.IsType(t, &whatsapp.CloudClient{}, client)
+}
@@ -0,0 +1,80 @@
+package wingo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	// RetryableStatus lists the response status codes that are retried.
+	// Requests that fail without a response are always retried.
+	RetryableStatus []int
+	// RespectRetryAfter makes the client wait at least as long as the
+	// Retry-After header of the response asks for.
+	RespectRetryAfter bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     10,
+		InitialInterval: 5 * time.Second,
+		MaxInterval:     backoff.DefaultMaxInterval,
+		RetryableStatus: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooEarly,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RespectRetryAfter: true,
+	}
+}
+
+func (p RetryPolicy) isRetryable(statusCode int) bool {
+	for _, code := range p.RetryableStatus {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) backOff() backoff.BackOff {
+	boff := backoff.NewExponentialBackOff()
+	boff.InitialInterval = p.InitialInterval
+	boff.MaxInterval = p.MaxInterval
+	boff.MaxElapsedTime = 0
+	boff.Reset()
+	return boff
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
@@ -4,6 +4,7 @@ type FlightsInformation struct {
 	ExchangeRate int64       `json:"exchangeRate"`
 	AirportInfo  AirportInfo `json:"airportInfo"`
 	VueloIda     []VueloIda  `json:"vueloIda"`
+	VueloRegreso []VueloIda  `json:"vueloRegreso"`
 	Token        string      `json:"token"`
 }
 